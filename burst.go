@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// Burst detection thresholds. Errors closer together than burstGap belong to
+// the same burst; a burst needs at least minBurstSize errors to count.
+const (
+	burstGap     = 30 * time.Second
+	minBurstSize = 5
+)
+
+// Root-cause candidate kinds (an error can be both)
+const (
+	RootCauseFirst         = 1 << iota // Earliest error in a burst
+	RootCauseFirstCritical             // Earliest critical in a burst
+)
+
+// Burst is a run of errors with no gap longer than burstGap
+type Burst struct {
+	Start      time.Time
+	End        time.Time
+	Count      int
+	Components int
+}
+
+// RootCause marks an error as a likely root cause of the burst it starts
+type RootCause struct {
+	Kind  int
+	Burst *Burst
+}
+
+// findRootCauses detects bursts in chronologically sorted errors (as returned by
+// Store.Errors) and returns the root-cause candidates keyed by error ID
+func findRootCauses(errors []daqlog.Error) map[int]RootCause {
+	causes := make(map[int]RootCause)
+
+	var current []daqlog.Error
+	var lastTime time.Time
+
+	flush := func() {
+		if len(current) < minBurstSize {
+			current = nil
+			return
+		}
+		first, _ := daqlog.ErrorTime(current[0])
+		last, _ := daqlog.ErrorTime(current[len(current)-1])
+		components := make(map[string]bool)
+		for _, e := range current {
+			components[e.Component] = true
+		}
+		b := &Burst{Start: first, End: last, Count: len(current), Components: len(components)}
+
+		causes[current[0].ID] = RootCause{Kind: RootCauseFirst, Burst: b}
+		for _, e := range current {
+			if e.LogLevel == "C" {
+				rc := causes[e.ID]
+				rc.Kind |= RootCauseFirstCritical
+				rc.Burst = b
+				causes[e.ID] = rc
+				break
+			}
+		}
+		current = nil
+	}
+
+	for _, e := range errors {
+		t, ok := daqlog.ErrorTime(e)
+		if !ok {
+			continue
+		}
+		if len(current) > 0 && t.Sub(lastTime) > burstGap {
+			flush()
+		}
+		current = append(current, e)
+		lastTime = t
+	}
+	flush()
+
+	return causes
+}
+
+// rootCauseBadge returns the badge shown next to a root-cause candidate
+func rootCauseBadge(kind int) string {
+	switch {
+	case kind&RootCauseFirst != 0 && kind&RootCauseFirstCritical != 0:
+		return "★!"
+	case kind&RootCauseFirstCritical != 0:
+		return "!"
+	case kind&RootCauseFirst != 0:
+		return "★"
+	}
+	return ""
+}
+
+// rootCauseSummary describes why an error is a root-cause candidate
+func rootCauseSummary(rc RootCause) string {
+	var what string
+	switch {
+	case rc.Kind&RootCauseFirst != 0 && rc.Kind&RootCauseFirstCritical != 0:
+		what = "first error and first critical"
+	case rc.Kind&RootCauseFirstCritical != 0:
+		what = "first critical"
+	default:
+		what = "first error"
+	}
+	return fmt.Sprintf("%s of burst (%d errors, %d components, %s)",
+		what, rc.Burst.Count, rc.Burst.Components, rc.Burst.End.Sub(rc.Burst.Start).Round(time.Second))
+}
+
+// groupRootCause returns the combined candidate kinds for errors in a group
+func (m Model) groupRootCause(g ErrorGroup) int {
+	kind := 0
+	for _, e := range g.Errors {
+		kind |= m.rootCauses[e.ID].Kind
+	}
+	return kind
+}
+
+// jumpToRootCause moves the selection to the next (dir=1) or previous (dir=-1)
+// root-cause candidate in group order, wrapping around
+func (m *Model) jumpToRootCause(dir int) {
+	type pos struct{ group, err int }
+	var candidates []pos
+	for gi, g := range m.groups {
+		for ei, e := range g.Errors {
+			if _, ok := m.rootCauses[e.ID]; ok {
+				candidates = append(candidates, pos{gi, ei})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	before := func(a, b pos) bool {
+		return a.group < b.group || (a.group == b.group && a.err < b.err)
+	}
+	cur := pos{m.groupCursor, m.errorCursor}
+
+	target := candidates[0]
+	if dir > 0 {
+		for _, c := range candidates {
+			if before(cur, c) {
+				target = c
+				break
+			}
+		}
+	} else {
+		target = candidates[len(candidates)-1]
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if before(candidates[i], cur) {
+				target = candidates[i]
+				break
+			}
+		}
+	}
+
+	m.markJump()
+	m.messageFilter = ""
+	m.groupCursor = target.group
+	m.errorCursor = target.err
+	pageSize := m.height - 10
+	if pageSize < 5 {
+		pageSize = 5
+	}
+	m.groupOffset = (m.groupCursor / pageSize) * pageSize
+	m.errorOffset = (m.errorCursor / pageSize) * pageSize
+	m.updateContextPane()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"container/list"
+	"context"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Context lines are fetched on demand and kept in a bounded LRU
+const (
+	contextCacheSize = 512 // Errors whose context is kept
+	contextPrefetch  = 8   // Neighbours fetched on each side of the selection
+)
+
+// contextCache is an LRU of error context by error ID. Model copies share it by
+// pointer; only Update touches it, so it needs no locking.
+type contextCache struct {
+	size     int
+	order    *list.List            // Front is most recently used
+	items    map[int]*list.Element // Error ID -> element holding a contextEntry
+	inflight map[int]bool          // IDs being fetched
+}
+
+type contextEntry struct {
+	id  int
+	ctx daqlog.ErrorContext
+}
+
+func newContextCache(size int) *contextCache {
+	return &contextCache{
+		size:     size,
+		order:    list.New(),
+		items:    make(map[int]*list.Element),
+		inflight: make(map[int]bool),
+	}
+}
+
+// get returns an error's context and marks it recently used
+func (c *contextCache) get(id int) (daqlog.ErrorContext, bool) {
+	el, ok := c.items[id]
+	if !ok {
+		return daqlog.ErrorContext{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(contextEntry).ctx, true
+}
+
+// add stores an error's context, evicting the least recently used beyond size
+func (c *contextCache) add(id int, ctx daqlog.ErrorContext) {
+	if el, ok := c.items[id]; ok {
+		el.Value = contextEntry{id, ctx}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[id] = c.order.PushFront(contextEntry{id, ctx})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(contextEntry).id)
+	}
+}
+
+// contextMsg delivers fetched context lines
+type contextMsg struct {
+	ids      []int // Every ID requested, found or not
+	contexts map[int]daqlog.ErrorContext
+	err      error
+}
+
+// withContext fills in e's context lines from the cache
+func (m *Model) withContext(e daqlog.Error) (daqlog.Error, bool) {
+	c, ok := m.contexts.get(e.ID)
+	if ok {
+		e.ContextBefore = c.Before
+		e.ContextAfter = c.After
+	}
+	return e, ok
+}
+
+// contextPending is shown in place of context lines that are not loaded yet
+func (m *Model) contextPending() string {
+	if m.contextErr != nil {
+		return criticalStyle.Render("Context unavailable: " + m.contextErr.Error())
+	}
+	return helpStyle.Render("Loading context…")
+}
+
+// contextWanted lists the selected error followed by its neighbours, nearest
+// first, and the first error of the adjacent groups
+func (m *Model) contextWanted() []int {
+	errors := m.getFilteredGroupErrors()
+	if m.errorCursor >= len(errors) {
+		return nil
+	}
+
+	ids := []int{errors[m.errorCursor].ID}
+	for d := 1; d <= contextPrefetch; d++ {
+		if i := m.errorCursor + d; i < len(errors) {
+			ids = append(ids, errors[i].ID)
+		}
+		if i := m.errorCursor - d; i >= 0 {
+			ids = append(ids, errors[i].ID)
+		}
+	}
+	for _, g := range []int{m.groupCursor + 1, m.groupCursor - 1} {
+		if g >= 0 && g < len(m.groups) && len(m.groups[g].Errors) > 0 {
+			ids = append(ids, m.groups[g].Errors[0].ID)
+		}
+	}
+	return ids
+}
+
+// fetchContext loads context for the selection and its neighbours that is
+// neither cached nor already being fetched
+func (m *Model) fetchContext() tea.Cmd {
+	if m.mode != ModeErrorList || len(m.groups) == 0 {
+		return nil
+	}
+
+	var missing []int
+	for _, id := range m.contextWanted() {
+		if _, ok := m.contexts.items[id]; ok || m.contexts.inflight[id] {
+			continue
+		}
+		m.contexts.inflight[id] = true
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	store := m.store
+	return func() tea.Msg {
+		contexts, err := store.Contexts(context.Background(), missing)
+		return contextMsg{ids: missing, contexts: contexts, err: err}
+	}
+}
+
+// handleContext caches fetched context and redraws the pane if it was waiting
+func (m *Model) handleContext(msg contextMsg) {
+	for _, id := range msg.ids {
+		delete(m.contexts.inflight, id)
+	}
+	m.contextErr = msg.err
+	if msg.err != nil {
+		m.updateContextPane()
+		return
+	}
+
+	selected := -1
+	if e := m.selectedError(); e != nil && m.mode == ModeErrorList {
+		selected = e.ID
+	}
+	for _, id := range msg.ids {
+		// Missing rows are cached as empty so they are not fetched again
+		m.contexts.add(id, msg.contexts[id])
+		if id == selected {
+			m.updateContextPane()
+		}
+	}
+}
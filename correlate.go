@@ -0,0 +1,515 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Correlation window bounds (seconds either side of the anchor error)
+const (
+	defaultCorrWindow = 10
+	minCorrWindow     = 1
+	maxCorrWindow     = 600
+)
+
+// CorrelatedError is an error within the correlation window of the anchor
+type CorrelatedError struct {
+	daqlog.Error
+	Offset time.Duration // Time relative to the anchor (negative = before)
+}
+
+// openCorrelation builds the correlation list around the selected error. Other
+// hutches' errors, when needed and not cached, are loaded in the background and
+// merged in when they arrive.
+func (m *Model) openCorrelation() tea.Cmd {
+	e := m.selectedError()
+	if e == nil {
+		return nil
+	}
+	anchor := *e
+	m.corrAnchor = &anchor
+	if m.corrWindow == 0 {
+		m.corrWindow = defaultCorrWindow
+	}
+	m.buildCorrelation()
+	m.mode = ModeCorrelation
+
+	// Start the cursor on the anchor itself
+	m.corrCursor = 0
+	for i, c := range m.correlated {
+		if c.ID == anchor.ID && c.Hutch == anchor.Hutch {
+			m.corrCursor = i
+			break
+		}
+	}
+	m.corrOffset = 0
+	m.scrollCorrelation()
+	return m.loadCorrelationHutches()
+}
+
+// buildCorrelation collects every error within ±corrWindow seconds of the anchor,
+// sorted by time offset from it
+func (m *Model) buildCorrelation() {
+	m.correlated = nil
+	if m.corrAnchor == nil {
+		return
+	}
+
+	anchorTime, ok := daqlog.ErrorTime(*m.corrAnchor)
+	if !ok {
+		// Without a time there is nothing to correlate against
+		m.correlated = []CorrelatedError{{Error: *m.corrAnchor}}
+		return
+	}
+	window := time.Duration(m.corrWindow) * time.Second
+
+	candidates := m.allErrors
+	if m.corrExtraMatches() {
+		candidates = append([]daqlog.Error(nil), m.allErrors...)
+		for _, e := range m.corrExtra {
+			// corrExtra may hold more hutches than are shown now, and fewer
+			// dates while a wider window is loading
+			if m.corrAllHutches || e.Hutch == m.selectedHutch {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+
+	for _, e := range candidates {
+		t, ok := daqlog.ErrorTime(e)
+		if !ok {
+			continue
+		}
+		offset := t.Sub(anchorTime)
+		if offset < -window || offset > window {
+			continue
+		}
+		m.correlated = append(m.correlated, CorrelatedError{Error: e, Offset: offset})
+	}
+
+	sort.SliceStable(m.correlated, func(i, j int) bool {
+		if m.correlated[i].Offset != m.correlated[j].Offset {
+			return m.correlated[i].Offset < m.correlated[j].Offset
+		}
+		if m.correlated[i].Hutch != m.correlated[j].Hutch {
+			return m.correlated[i].Hutch < m.correlated[j].Hutch
+		}
+		return m.correlated[i].LineNumber < m.correlated[j].LineNumber
+	})
+}
+
+// correlationDates lists the Pacific dates the correlation window touches: the
+// selected date, and the day before or after when the window around the
+// anchor crosses midnight
+func (m *Model) correlationDates() []string {
+	dates := []string{m.selectedDate}
+	if m.corrAnchor == nil {
+		return dates
+	}
+	anchorTime, ok := daqlog.ErrorTime(*m.corrAnchor)
+	if !ok {
+		return dates
+	}
+	window := time.Duration(m.corrWindow) * time.Second
+	for _, edge := range []time.Time{anchorTime.Add(-window), anchorTime.Add(window)} {
+		if date := edge.In(daqlog.PacificLoc).Format("2006-01-02"); !slices.Contains(dates, date) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// corrExtraMatches reports whether corrExtra was loaded around the selected
+// hutch and date, so it adds to the selected date's errors without repeating
+// them
+func (m *Model) corrExtraMatches() bool {
+	return m.corrExtraHutch == m.selectedHutch && len(m.corrExtraDates) > 0 && m.corrExtraDates[0] == m.selectedDate
+}
+
+// corrExtraCovers reports whether corrExtra holds every error the correlation
+// needs beyond the selected hutch's date
+func (m *Model) corrExtraCovers() bool {
+	if !m.corrExtraMatches() || (m.corrAllHutches && !m.corrExtraAll) {
+		return false
+	}
+	for _, date := range m.correlationDates() {
+		if !slices.Contains(m.corrExtraDates, date) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadCorrelationHutches loads the errors the correlation needs beyond the
+// selected hutch's date, then rebuilds the list: every other hutch when all
+// hutches are shown, and the adjacent date when the window crosses midnight.
+// Results are kept until the hutch changes or a wider window or the other
+// hutches need more, so toggling and resizing the window stays cheap.
+func (m *Model) loadCorrelationHutches() tea.Cmd {
+	if m.corrExtraCovers() {
+		return nil
+	}
+
+	store, selected, all, dates := m.store, m.selectedHutch, m.corrAllHutches, m.correlationDates()
+	var others []string
+	if all {
+		for _, h := range m.hutches {
+			if h.Hutch != selected {
+				others = append(others, h.Hutch)
+			}
+		}
+	}
+	if len(others) == 0 && len(dates) == 1 {
+		// Everything is in the selected date's errors
+		m.corrExtra, m.corrExtraHutch, m.corrExtraDates, m.corrExtraAll = nil, selected, dates, all
+		return nil
+	}
+
+	label := "Loading other hutches"
+	if !all {
+		label = "Loading " + strings.Join(dates[1:], ", ")
+	}
+	return m.startLoad(label, func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		extra := []daqlog.Error{}
+		for _, date := range dates {
+			hutches := others
+			if date != dates[0] {
+				hutches = append([]string{selected}, others...)
+			}
+			for _, hutch := range hutches {
+				errors, err := store.Errors(ctx, hutch, date)
+				if err != nil {
+					return nil, err
+				}
+				extra = append(extra, errors...)
+			}
+		}
+		return func(m *Model) tea.Cmd {
+			m.corrExtra, m.corrExtraHutch, m.corrExtraDates, m.corrExtraAll = extra, selected, dates, all
+			m.rebuildCorrelationKeepCursor()
+			return nil
+		}, nil
+	})
+}
+
+// resizeCorrelation changes the window and keeps the cursor on the same error
+func (m *Model) resizeCorrelation(window int) {
+	if window < minCorrWindow {
+		window = minCorrWindow
+	}
+	if window > maxCorrWindow {
+		window = maxCorrWindow
+	}
+	m.corrWindow = window
+	m.rebuildCorrelationKeepCursor()
+}
+
+// rebuildCorrelationKeepCursor rebuilds the list and restores the cursor by error identity
+func (m *Model) rebuildCorrelationKeepCursor() {
+	var currentID int
+	var currentHutch string
+	if m.corrCursor < len(m.correlated) {
+		currentID = m.correlated[m.corrCursor].ID
+		currentHutch = m.correlated[m.corrCursor].Hutch
+	}
+
+	m.buildCorrelation()
+
+	m.corrCursor = 0
+	for i, c := range m.correlated {
+		if c.ID == currentID && c.Hutch == currentHutch {
+			m.corrCursor = i
+			break
+		}
+	}
+	m.scrollCorrelation()
+}
+
+// scrollCorrelation keeps the correlation cursor inside the visible page
+func (m *Model) scrollCorrelation() {
+	visibleCount := m.height - 8
+	if visibleCount < 5 {
+		visibleCount = 5
+	}
+	if m.corrCursor < m.corrOffset {
+		m.corrOffset = m.corrCursor
+	}
+	if m.corrCursor >= m.corrOffset+visibleCount {
+		m.corrOffset = m.corrCursor - visibleCount + 1
+	}
+}
+
+// jumpToCorrelated selects the error under the correlation cursor in the error list,
+// switching hutch if it came from another one
+func (m *Model) jumpToCorrelated() tea.Cmd {
+	if m.corrCursor >= len(m.correlated) {
+		return nil
+	}
+	target := m.correlated[m.corrCursor].Error
+
+	// Errors from the adjacent day belong to another date's list
+	date := target.DateRef
+	if date == "" {
+		date = m.selectedDate
+	}
+	if (target.Hutch == "" || target.Hutch == m.selectedHutch) && date == m.selectedDate {
+		m.selectInErrorList(target.ID)
+		return nil
+	}
+	if target.Hutch == "" {
+		target.Hutch = m.selectedHutch
+	}
+
+	store := m.store
+	return m.startLoad("Loading "+target.Hutch+" "+date, func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		errors, err := store.Errors(ctx, target.Hutch, date)
+		if err != nil {
+			return nil, err
+		}
+		dates, err := store.Dates(ctx, target.Hutch)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			m.selectedHutch = target.Hutch
+			m.selectedDate = date
+			for i, h := range m.hutches {
+				if h.Hutch == target.Hutch {
+					m.hutchCursor = i
+					break
+				}
+			}
+			m.dates = dates
+			m.allErrors = errors
+			m.levelFilter = ""
+			m.componentFilter = ""
+			m.messageFilter = ""
+			m.filteredErrors = errors
+			m.buildGroups()
+			m.corrExtra, m.corrExtraHutch, m.corrExtraDates = nil, "", nil
+			m.selectInErrorList(target.ID)
+			return nil
+		}, nil
+	})
+}
+
+// selectInErrorList returns to the error list with an error selected, clearing
+// filters that hide it
+func (m *Model) selectInErrorList(errorID int) {
+	m.markJump()
+	m.mode = ModeErrorList
+	m.focusedPanel = PanelErrors
+	if !m.groupsContain(errorID) {
+		// The target is hidden by the current filters
+		m.levelFilter = ""
+		m.componentFilter = ""
+		m.filterInput.SetValue("")
+		m.filteredErrors = m.allErrors
+		m.buildGroups()
+	}
+	m.messageFilter = ""
+	m.findAndSelectError(errorID)
+	m.updateContextPane()
+}
+
+// groupsContain reports whether an error ID is present in the current groups
+func (m *Model) groupsContain(errorID int) bool {
+	for _, g := range m.groups {
+		for _, e := range g.Errors {
+			if e.ID == errorID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m Model) updateCorrelation(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	visibleCount := m.height - 8
+	if visibleCount < 5 {
+		visibleCount = 5
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back):
+		m.mode = ModeErrorList
+
+	case key.Matches(msg, m.keys.Up):
+		if m.corrCursor > 0 {
+			m.corrCursor--
+		}
+
+	case key.Matches(msg, m.keys.Down):
+		if m.corrCursor < len(m.correlated)-1 {
+			m.corrCursor++
+		}
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.corrCursor -= visibleCount
+		if m.corrCursor < 0 {
+			m.corrCursor = 0
+		}
+
+	case key.Matches(msg, m.keys.PageDown):
+		m.corrCursor += visibleCount
+		if m.corrCursor >= len(m.correlated) {
+			m.corrCursor = len(m.correlated) - 1
+		}
+		if m.corrCursor < 0 {
+			m.corrCursor = 0
+		}
+
+	case key.Matches(msg, m.keys.Home):
+		m.corrCursor = 0
+
+	case key.Matches(msg, m.keys.End):
+		m.corrCursor = len(m.correlated) - 1
+		if m.corrCursor < 0 {
+			m.corrCursor = 0
+		}
+
+	case key.Matches(msg, m.keys.Enter):
+		return m, m.jumpToCorrelated()
+
+	case key.Matches(msg, m.keys.WindowWider):
+		m.resizeCorrelation(m.corrWindow * 2)
+		m.scrollCorrelation()
+		return m, m.loadCorrelationHutches()
+
+	case key.Matches(msg, m.keys.WindowNarrower):
+		m.resizeCorrelation(m.corrWindow / 2)
+
+	case key.Matches(msg, m.keys.AllHutches):
+		m.corrAllHutches = !m.corrAllHutches
+		m.rebuildCorrelationKeepCursor()
+		return m, m.loadCorrelationHutches()
+
+	case key.Matches(msg, m.keys.Zone):
+		m.cycleZone()
+
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = !m.showHelp
+	}
+
+	m.scrollCorrelation()
+	return m, nil
+}
+
+func (m Model) viewCorrelation() string {
+	var sb strings.Builder
+
+	scope := strings.ToUpper(m.selectedHutch)
+	if m.corrAllHutches {
+		scope = "all hutches"
+	}
+	title := titleStyle.Render(fmt.Sprintf("Correlation ±%ds - %s - %s - %s", m.corrWindow, scope, m.selectedDate, zoneName(m.displayLoc)))
+	sb.WriteString(title)
+	sb.WriteString("\n\n")
+
+	if m.corrAnchor != nil {
+		a := m.corrAnchor
+		anchorTime := "??:??:??"
+		if t, ok := daqlog.ErrorTime(*a); ok {
+			anchorTime = daqlog.FormatClock(t, m.displayLoc, "15:04:05")
+		}
+		sb.WriteString(contextHeaderStyle.Render("Anchor: "))
+		sb.WriteString(fmt.Sprintf("%s %s @ %s [%s] %s",
+			anchorTime, a.Component, a.Host, a.LogLevel, truncate(a.Message, max(m.width-40, 20))))
+		sb.WriteString("\n\n")
+	}
+
+	if len(m.correlated) == 0 {
+		sb.WriteString(helpStyle.Render("No errors in window"))
+		sb.WriteString("\n")
+	}
+
+	visibleCount := m.height - 8
+	if visibleCount < 5 {
+		visibleCount = 5
+	}
+	start := m.corrOffset
+	end := start + visibleCount
+	if end > len(m.correlated) {
+		end = len(m.correlated)
+	}
+
+	msgWidth := m.width - 60
+	if msgWidth < 20 {
+		msgWidth = 20
+	}
+
+	for i := start; i < end; i++ {
+		c := m.correlated[i]
+
+		cursor := "  "
+		if i == m.corrCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+
+		marker := " "
+		if m.corrAnchor != nil && c.ID == m.corrAnchor.ID && c.Hutch == m.corrAnchor.Hutch {
+			marker = "◆"
+		}
+
+		clock := "??:??:??"
+		if t, ok := daqlog.ErrorTime(c.Error); ok {
+			clock = daqlog.FormatClock(t, m.displayLoc, "15:04:05")
+		}
+
+		comp := c.Component
+		if len(comp) > 12 {
+			comp = comp[:9] + "..."
+		}
+		host := c.Host
+		if len(host) > 16 {
+			host = host[:13] + "..."
+		}
+
+		line := fmt.Sprintf("%s %6s %-4s %s %-12s %-16s ",
+			marker, formatOffset(c.Offset), strings.ToUpper(c.Hutch), clock, comp, host)
+		level := ErrorLevelStyle(c.LogLevel, c.ErrorType).Render(fmt.Sprintf("[%s]", c.LogLevel))
+
+		if i == m.corrCursor {
+			line = selectedStyle.Render(line)
+		} else {
+			line = normalStyle.Render(line)
+		}
+
+		sb.WriteString(cursor)
+		sb.WriteString(line)
+		sb.WriteString(level)
+		sb.WriteString(" ")
+		sb.WriteString(truncate(c.Message, msgWidth))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	if m.showHelp {
+		sb.WriteString(m.help.View(m.keys))
+	} else {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("%d errors  ↑↓ nav  enter jump  +/- window  H all hutches  Z zone  esc back  q quit", len(m.correlated))))
+	}
+
+	return sb.String()
+}
+
+// formatOffset renders a signed offset like "-3s" or "+12s"
+func formatOffset(d time.Duration) string {
+	secs := int(d / time.Second)
+	if secs == 0 {
+		return "0s"
+	}
+	return fmt.Sprintf("%+ds", secs)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runLoads runs cmd and applies the loads it finishes, as Update would
+func runLoads(m *Model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			runLoads(m, c)
+		}
+	case loadedMsg:
+		runLoads(m, m.handleLoaded(msg))
+	}
+}
+
+// correlatedIDs lists the IDs in the correlation view in order
+func correlatedIDs(m *Model) string {
+	ids := []int{}
+	for _, c := range m.correlated {
+		ids = append(ids, c.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestCorrelationAcrossMidnight(t *testing.T) {
+	// Pacific midnight between 2025-11-19 and 2025-11-20 is 08:00 UTC
+	var errors []daqlog.Error
+	add := func(hutch, date, ts string) {
+		errors = append(errors, daqlog.Error{
+			ID: len(errors) + 1, Hutch: hutch, DateRef: date, Timestamp: ts,
+			Component: "drp1", LogLevel: "E", ErrorType: "timeout", Message: "Timeout",
+			FilePath: "/logs/" + hutch + "/drp1.log",
+		})
+	}
+	add("tmo", "2025-11-19", "2025-11-20 07:59:55") // 1: anchor, 23:59:55
+	add("tmo", "2025-11-19", "2025-11-19 20:00:00") // 2: noon
+	add("tmo", "2025-11-20", "2025-11-20 08:00:03") // 3: 00:00:03 the next day
+	add("rix", "2025-11-19", "2025-11-20 07:59:58") // 4
+	add("rix", "2025-11-20", "2025-11-20 08:00:05") // 5
+	add("rix", "2025-11-20", "2025-11-20 09:00:00") // 6: outside any window
+	store := daqlog.NewMemoryStore(errors)
+
+	m := NewModel(store, nil, viewState{}, "")
+	m.hutches = []daqlog.HutchSummary{{Hutch: "rix"}, {Hutch: "tmo"}}
+	m.selectedHutch, m.selectedDate = "tmo", "2025-11-19"
+	loaded, err := store.Errors(context.Background(), "tmo", "2025-11-19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.showErrors(loaded)
+
+	open := func(id int) {
+		t.Helper()
+		for _, e := range m.allErrors {
+			if e.ID == id {
+				anchor := e
+				m.corrAnchor = &anchor
+			}
+		}
+		m.buildCorrelation()
+		runLoads(&m, m.loadCorrelationHutches())
+	}
+
+	m.corrWindow = 10
+	open(1)
+	if got := correlatedIDs(&m); got != "[1 3]" {
+		t.Errorf("own hutch across midnight = %s, want [1 3]", got)
+	}
+
+	m.corrAllHutches = true
+	runLoads(&m, m.loadCorrelationHutches())
+	if got := correlatedIDs(&m); got != "[1 4 3 5]" {
+		t.Errorf("all hutches across midnight = %s, want [1 4 3 5]", got)
+	}
+
+	// Narrowing stays inside the loaded dates without another load
+	m.resizeCorrelation(2)
+	if cmd := m.loadCorrelationHutches(); cmd != nil {
+		t.Error("narrowing the window started a load")
+	}
+	if got := correlatedIDs(&m); got != "[1]" {
+		t.Errorf("±2s = %s, want [1]", got)
+	}
+
+	// Away from midnight one hutch needs nothing beyond the selected date
+	m.corrExtra, m.corrExtraHutch, m.corrExtraDates = nil, "", nil
+	m.corrAllHutches, m.corrWindow = false, 10
+	open(2)
+	if m.loading != nil || len(m.corrExtraDates) != 1 || correlatedIDs(&m) != "[2]" {
+		t.Errorf("around noon: loading %v, dates %v, list %s; want no load of only 2025-11-19 and [2]",
+			m.loading != nil, m.corrExtraDates, correlatedIDs(&m))
+	}
+
+	// Jumping to an error from the next day opens that date
+	open(1)
+	m.corrCursor = 1 // Error 3
+	runLoads(&m, m.jumpToCorrelated())
+	if e := m.selectedError(); m.selectedDate != "2025-11-20" || e == nil || e.ID != 3 {
+		t.Errorf("after the jump: date %s, selected %v; want error 3 on 2025-11-20", m.selectedDate, e)
+	}
+}
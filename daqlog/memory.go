@@ -0,0 +1,159 @@
+package daqlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MemoryStore serves a fixed set of errors from memory, for tests and for tools
+// that build errors from another source
+type MemoryStore struct {
+	errors []Error
+	byID   map[int]int // Error ID -> index in errors
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a store over a copy of errors. Each error needs its
+// Hutch and DateRef (Pacific date) set; errors without an ID are numbered
+// after the largest ID given, and unresolved times are resolved.
+func NewMemoryStore(errors []Error) *MemoryStore {
+	s := &MemoryStore{
+		errors: append([]Error(nil), errors...),
+		byID:   make(map[int]int, len(errors)),
+	}
+
+	next := 0
+	for _, e := range s.errors {
+		next = max(next, e.ID)
+	}
+	for i := range s.errors {
+		if s.errors[i].ID == 0 {
+			next++
+			s.errors[i].ID = next
+		}
+		if s.errors[i].TimeSource == TimeUnresolved {
+			s.errors[i].ResolveTime()
+		}
+		s.byID[s.errors[i].ID] = i
+	}
+	return s
+}
+
+// Hutches returns hutches that have errors, sorted alphabetically. Each distinct
+// file path counts as one file.
+func (s *MemoryStore) Hutches(ctx context.Context) ([]HutchSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	byHutch := make(map[string]*HutchSummary)
+	files := make(map[string]map[string]bool)
+	for _, e := range s.errors {
+		h, ok := byHutch[e.Hutch]
+		if !ok {
+			h = &HutchSummary{Hutch: e.Hutch}
+			byHutch[e.Hutch] = h
+			files[e.Hutch] = make(map[string]bool)
+		}
+		h.ErrorCount++
+		files[e.Hutch][e.FilePath] = true
+	}
+
+	var hutches []HutchSummary
+	for name, h := range byHutch {
+		h.FileCount = len(files[name])
+		hutches = append(hutches, *h)
+	}
+	sort.Slice(hutches, func(i, j int) bool {
+		return hutches[i].Hutch < hutches[j].Hutch
+	})
+	return hutches, nil
+}
+
+// Dates returns the Pacific dates with errors for a hutch, latest first
+func (s *MemoryStore) Dates(ctx context.Context, hutch string) ([]DateSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*DateSummary)
+	files := make(map[string]map[string]bool)
+	for _, e := range s.errors {
+		if e.Hutch != hutch || e.DateRef == "" {
+			continue
+		}
+		d, ok := byDate[e.DateRef]
+		if !ok {
+			d = &DateSummary{Date: e.DateRef}
+			byDate[e.DateRef] = d
+			files[e.DateRef] = make(map[string]bool)
+		}
+		d.ErrorCount++
+		files[e.DateRef][e.FilePath] = true
+	}
+
+	var dates []DateSummary
+	for date, d := range byDate {
+		d.FileCount = len(files[date])
+		dates = append(dates, *d)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Date > dates[j].Date
+	})
+	if len(dates) > maxDates {
+		dates = dates[:maxDates]
+	}
+	return dates, nil
+}
+
+// Errors returns a hutch's errors for a Pacific date in chronological order
+func (s *MemoryStore) Errors(ctx context.Context, hutch, pacificDate string) ([]Error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, _, err := PacificDateToUTCRange(pacificDate); err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	var errors []Error
+	for _, e := range s.errors {
+		if e.Hutch == hutch && e.DateRef == pacificDate && !isNoise(e) {
+			e.ContextBefore, e.ContextAfter = "", ""
+			errors = append(errors, e)
+		}
+	}
+	sortErrors(errors)
+	return errors, nil
+}
+
+// Contexts returns context lines for errors by ID
+func (s *MemoryStore) Contexts(ctx context.Context, ids []int) (map[int]ErrorContext, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	contexts := make(map[int]ErrorContext, len(ids))
+	for _, id := range ids {
+		if i, ok := s.byID[id]; ok {
+			contexts[id] = ErrorContext{Before: s.errors[i].ContextBefore, After: s.errors[i].ContextAfter}
+		}
+	}
+	return contexts, nil
+}
+
+// ErrorByID returns a single error
+func (s *MemoryStore) ErrorByID(ctx context.Context, id int) (*Error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	i, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("error %d: %w", id, ErrNotFound)
+	}
+	e := s.errors[i]
+	return &e, nil
+}
@@ -0,0 +1,210 @@
+package daqlog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema versions written by the ingester. Version 1 predates per-error
+// timestamps, context lines and the cached per-file error count; version 2
+// is the current layout. Databases without a metadata table are assigned a
+// version from the columns they have.
+const (
+	SchemaV1      = 1
+	SchemaV2      = 2
+	SchemaCurrent = SchemaV2
+)
+
+// Schema describes the layout of an opened database and the SQL expressions
+// that read it. Expressions are written against the aliases lf (log_files)
+// and le (log_errors), and stand in a NULL or computed value for optional
+// columns the database does not have.
+type Schema struct {
+	Version  int
+	Declared bool     // Version came from the metadata table rather than the columns
+	Warnings []string // Non-fatal differences from the layouts this build knows
+
+	FileErrorCount string // Errors in lf
+	ErrorTimestamp string // le timestamp (UTC text, may be time-only)
+	ContextBefore  string
+	ContextAfter   string
+}
+
+// requiredColumns are the columns every known layout has. A missing one is a
+// SchemaError.
+var requiredColumns = map[string][]string{
+	"log_files":  {"id", "file_path", "hutch", "host", "component", "start_timestamp_utc"},
+	"log_errors": {"id", "log_file_id", "line_number", "log_level", "error_type", "message"},
+}
+
+// SchemaError reports a table or column the browser needs but the database
+// lacks
+type SchemaError struct {
+	Database string // File the table belongs to, set when several are open
+	Table    string
+	Column   string   // Empty when the whole table is missing
+	Found    []string // Columns the table does have
+	Version  int      // Declared or inferred schema version, 0 if unknown
+}
+
+func (e *SchemaError) Error() string {
+	var b strings.Builder
+	if e.Database != "" {
+		b.WriteString(e.Database + ": ")
+	}
+	if e.Column == "" {
+		fmt.Fprintf(&b, "table %q not found: this does not look like a daq_logs.db written by ingest_daq_logs.py", e.Table)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "table %q has no column %q", e.Table, e.Column)
+	if e.Version > 0 {
+		fmt.Fprintf(&b, " (schema version %d)", e.Version)
+	}
+	fmt.Fprintf(&b, "; found columns: %s.", strings.Join(e.Found, ", "))
+	fmt.Fprintf(&b, " Rebuild the database with an ingest_daq_logs.py that writes schema version %d", SchemaCurrent)
+	return b.String()
+}
+
+// DetectSchema inspects log_files and log_errors in the main database and
+// works out how to read them. It fails with a *SchemaError when a required
+// table or column is missing.
+func DetectSchema(ctx context.Context, db *sql.DB) (*Schema, error) {
+	return detectSchema(ctx, db, "main")
+}
+
+// detectSchema is DetectSchema for the attached database named dbName
+func detectSchema(ctx context.Context, db *sql.DB, dbName string) (*Schema, error) {
+	prefix := quoteIdent(dbName) + "."
+	files, err := tableColumns(ctx, db, prefix, "log_files")
+	if err != nil {
+		return nil, err
+	}
+	errs, err := tableColumns(ctx, db, prefix, "log_errors")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	s.Version, s.Declared, err = declaredVersion(ctx, db, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Declared {
+		// No metadata table: the per-error timestamp arrived with version 2
+		s.Version = SchemaV1
+		if errs["timestamp_utc"] {
+			s.Version = SchemaV2
+		}
+	}
+
+	for _, table := range []string{"log_files", "log_errors"} {
+		cols := files
+		if table == "log_errors" {
+			cols = errs
+		}
+		for _, c := range requiredColumns[table] {
+			if !cols[c] {
+				return nil, &SchemaError{Table: table, Column: c, Found: sortedKeys(cols), Version: s.Version}
+			}
+		}
+	}
+
+	s.FileErrorCount = "(SELECT COUNT(*) FROM " + prefix + "log_errors x WHERE x.log_file_id = lf.id)"
+	if files["error_count"] {
+		s.FileErrorCount = "lf.error_count"
+	}
+	s.ErrorTimestamp = optionalColumn(errs, "timestamp_utc")
+	s.ContextBefore = optionalColumn(errs, "context_before")
+	s.ContextAfter = optionalColumn(errs, "context_after")
+
+	if s.Version > SchemaCurrent {
+		s.Warnings = append(s.Warnings, fmt.Sprintf(
+			"database schema version %d is newer than this browser knows (%d); reading the columns it recognizes",
+			s.Version, SchemaCurrent))
+	}
+	if s.Version >= SchemaV2 && s.ErrorTimestamp == "NULL" {
+		s.Warnings = append(s.Warnings,
+			"log_errors has no timestamp_utc column; error times fall back to the file name and start time")
+	}
+	return s, nil
+}
+
+// optionalColumn returns le.name, or NULL when the column is absent
+func optionalColumn(cols map[string]bool, name string) string {
+	if cols[name] {
+		return "le." + name
+	}
+	return "NULL"
+}
+
+// tableColumns returns the set of column names of a table. prefix is the
+// quoted database name and a dot.
+func tableColumns(ctx context.Context, db *sql.DB, prefix, table string) (map[string]bool, error) {
+	// PRAGMA arguments cannot be bound, but table is always one of our constants
+	rows, err := db.QueryContext(ctx, "PRAGMA "+prefix+"table_info("+table+")")
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+		}
+		cols[strings.ToLower(name)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	if len(cols) == 0 {
+		return nil, &SchemaError{Table: table}
+	}
+	return cols, nil
+}
+
+// declaredVersion reads schema_version from the optional metadata(key, value)
+// table
+func declaredVersion(ctx context.Context, db *sql.DB, prefix string) (int, bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM `+prefix+`sqlite_master WHERE type = 'table' AND name = 'metadata'`).Scan(&n)
+	if err != nil || n == 0 {
+		return 0, false, err
+	}
+
+	var value string
+	err = db.QueryRowContext(ctx, `SELECT value FROM `+prefix+`metadata WHERE key = 'schema_version'`).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || v < 1 {
+		return 0, false, fmt.Errorf("metadata schema_version %q is not a positive integer", value)
+	}
+	return v, true, nil
+}
+
+// quoteIdent quotes an SQL identifier such as an attached database name
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,505 @@
+package daqlog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore reads errors from the daq_logs.db written by the ingester. It
+// reads every database attached to the handle as one: log files found in more
+// than one are counted once, and an error line is kept from the first
+// database that has it.
+type SQLiteStore struct {
+	db      *sql.DB
+	sources []sqliteSource // main first, then attached in attach order
+}
+
+// sqliteSource is one database attached to the store's handle
+type sqliteSource struct {
+	prefix string // Quoted database name and a dot, for qualifying tables
+	file   string // Path of the database file
+	schema *Schema
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// Errors from the n-th database get IDs offset by n<<sourceShift so that IDs
+// stay unique across databases. The main database keeps its own IDs.
+const sourceShift = 40
+
+// NewSQLiteStore wraps an open database handle after checking the schema of
+// the main database and of every database attached to it. It fails with a
+// *SchemaError when a required table or column is missing.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "PRAGMA database_list")
+	if err != nil {
+		return nil, err
+	}
+	type attached struct{ name, file string }
+	var list []attached
+	for rows.Next() {
+		var seq int
+		var a attached
+		if err := rows.Scan(&seq, &a.name, &a.file); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if a.name != "temp" {
+			list = append(list, a)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: db}
+	for _, a := range list {
+		schema, err := detectSchema(ctx, db, a.name)
+		if schemaErr, ok := err.(*SchemaError); ok && len(list) > 1 {
+			schemaErr.Database = a.file
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.sources = append(s.sources, sqliteSource{prefix: quoteIdent(a.name) + ".", file: a.file, schema: schema})
+	}
+	return s, nil
+}
+
+// OpenSQLite opens one or more database files read-only in immutable mode (no
+// locking) and reads them as one store. Use NewSQLiteStore with a handle from
+// OpenSQLiteDB and "mode=ro" to follow a database that is still being written.
+func OpenSQLite(paths ...string) (*SQLiteStore, error) {
+	db, err := OpenSQLiteDB("immutable=1", paths...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	store, err := NewSQLiteStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// OpenSQLiteDB returns a handle on the first path with the others attached to
+// every connection, all opened with the given URI parameters (e.g.
+// "immutable=1"). SQLite attaches at most 10 databases to one connection.
+func OpenSQLiteDB(params string, paths ...string) (*sql.DB, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no database given")
+	}
+	uri := func(path string) string {
+		// Escaped so that "?", "#" or "%" in a file name stay part of the path
+		return (&url.URL{Scheme: "file", Path: path, RawQuery: params, OmitHost: true}).String()
+	}
+
+	drv := &sqlite3.SQLiteDriver{}
+	if len(paths) > 1 {
+		drv.ConnectHook = func(conn *sqlite3.SQLiteConn) error {
+			for i, path := range paths[1:] {
+				name := "src" + strconv.Itoa(i+1)
+				if _, err := conn.Exec("ATTACH DATABASE ? AS "+name, []driver.Value{uri(path)}); err != nil {
+					return fmt.Errorf("attaching %s: %w", path, err)
+				}
+			}
+			return nil
+		}
+	}
+	return sql.OpenDB(sqliteConnector{drv: drv, dsn: uri(paths[0])}), nil
+}
+
+// sqliteConnector opens connections through a driver with a ConnectHook, so
+// that a connection the pool opens later is attached like the first
+type sqliteConnector struct {
+	drv *sqlite3.SQLiteDriver
+	dsn string
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// DB returns the underlying database handle
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// Schema returns the layout detected for the main database
+func (s *SQLiteStore) Schema() *Schema {
+	return s.sources[0].schema
+}
+
+// Files returns the paths of the databases the store reads, main first
+func (s *SQLiteStore) Files() []string {
+	files := make([]string, len(s.sources))
+	for i, src := range s.sources {
+		files[i] = src.file
+	}
+	return files
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// sourceLabel is the Error.Source of errors from the i-th database: its file
+// when the store reads several, otherwise empty
+func (s *SQLiteStore) sourceLabel(i int) string {
+	if len(s.sources) < 2 {
+		return ""
+	}
+	return s.sources[i].file
+}
+
+// globalID and localID convert between a database's own error IDs and the
+// store's
+func globalID(src, id int) int {
+	return src<<sourceShift | id
+}
+
+func localID(id int) (src, local int) {
+	return id >> sourceShift, id & (1<<sourceShift - 1)
+}
+
+// fileUnion returns a query with one row per distinct log file across the
+// databases: src (the first database that has it), file_path, hutch,
+// start_timestamp_utc and errors. where filters log_files (alias lf) and is
+// repeated for each database along with its args.
+func (s *SQLiteStore) fileUnion(where string, args ...any) (string, []any) {
+	parts := make([]string, len(s.sources))
+	var allArgs []any
+	for i, src := range s.sources {
+		parts[i] = fmt.Sprintf(`SELECT %d AS src, lf.file_path, lf.hutch, lf.start_timestamp_utc, %s AS errors
+		FROM %slog_files lf WHERE %s`, i, src.schema.FileErrorCount, src.prefix, where)
+		allArgs = append(allArgs, args...)
+	}
+	if len(parts) == 1 {
+		return parts[0], allArgs
+	}
+	// SQLite takes the bare columns from the row holding the MIN
+	return `SELECT MIN(src) AS src, file_path, hutch, start_timestamp_utc, errors
+		FROM (` + strings.Join(parts, "\n\t\tUNION ALL ") + `)
+		GROUP BY file_path`, allArgs
+}
+
+// Hutches returns hutches that have errors, sorted alphabetically
+func (s *SQLiteStore) Hutches(ctx context.Context) ([]HutchSummary, error) {
+	files, args := s.fileUnion("1")
+	query := `
+		SELECT hutch, COUNT(*) as files, SUM(errors) as errors
+		FROM (` + files + `)
+		WHERE errors > 0
+		GROUP BY hutch
+		ORDER BY hutch
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hutches []HutchSummary
+	for rows.Next() {
+		var h HutchSummary
+		if err := rows.Scan(&h.Hutch, &h.FileCount, &h.ErrorCount); err != nil {
+			return nil, err
+		}
+		hutches = append(hutches, h)
+	}
+	return hutches, rows.Err()
+}
+
+// Dates returns dates (in Pacific time) that have errors for a specific hutch, sorted descending
+func (s *SQLiteStore) Dates(ctx context.Context, hutch string) ([]DateSummary, error) {
+	// Fetch individual file records to convert timestamps to Pacific time
+	files, args := s.fileUnion("lf.hutch = ?", hutch)
+	query := `
+		SELECT start_timestamp_utc, errors
+		FROM (` + files + `)
+		WHERE errors > 0
+		ORDER BY start_timestamp_utc DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Group by Pacific date in Go for proper DST handling. Each row is a
+	// distinct file.
+	type dateAgg struct {
+		fileCount  int
+		errorCount int
+	}
+	dateMap := make(map[string]*dateAgg)
+
+	for rows.Next() {
+		var timestampUTC string
+		var errorCount int
+		if err := rows.Scan(&timestampUTC, &errorCount); err != nil {
+			return nil, err
+		}
+
+		// Convert UTC timestamp to Pacific date
+		pacificDate := UTCTimestampToPacificDate(timestampUTC)
+		if pacificDate == "" {
+			continue
+		}
+
+		if agg, ok := dateMap[pacificDate]; ok {
+			agg.fileCount++
+			agg.errorCount += errorCount
+		} else {
+			dateMap[pacificDate] = &dateAgg{fileCount: 1, errorCount: errorCount}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Convert map to slice and sort
+	var dates []DateSummary
+	for date, agg := range dateMap {
+		dates = append(dates, DateSummary{
+			Date:       date,
+			FileCount:  agg.fileCount,
+			ErrorCount: agg.errorCount,
+		})
+	}
+
+	// Sort by date descending and limit
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Date > dates[j].Date
+	})
+	if len(dates) > maxDates {
+		dates = dates[:maxDates]
+	}
+
+	return dates, nil
+}
+
+// Errors loads errors for a specific hutch and Pacific date, ordered by timestamp
+func (s *SQLiteStore) Errors(ctx context.Context, hutch, pacificDate string) ([]Error, error) {
+	// Calculate UTC time range for the Pacific date
+	utcStart, utcEnd, err := PacificDateToUTCRange(pacificDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	// An error line already read from an earlier database is skipped
+	seen := make(map[string]bool)
+	var errors []Error
+	for i, src := range s.sources {
+		found, err := s.sourceErrors(ctx, i, src, hutch, pacificDate, utcStart, utcEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range found {
+			if !seen[errorLineKey(e)] {
+				errors = append(errors, e)
+			}
+		}
+		if len(s.sources) > 1 {
+			for _, e := range found {
+				seen[errorLineKey(e)] = true
+			}
+		}
+	}
+
+	sortErrors(errors)
+	return errors, nil
+}
+
+// errorLineKey identifies the log line an error was read from
+func errorLineKey(e Error) string {
+	return e.FilePath + ":" + strconv.Itoa(e.LineNumber)
+}
+
+// sourceErrors loads one database's errors for Errors
+func (s *SQLiteStore) sourceErrors(ctx context.Context, i int, src sqliteSource, hutch, pacificDate, utcStart, utcEnd string) ([]Error, error) {
+	query := `
+		SELECT le.id,
+		       COALESCE(` + src.schema.ErrorTimestamp + `, '') as timestamp,
+		       lf.component,
+		       lf.host,
+		       le.log_level,
+		       le.error_type,
+		       le.message,
+		       le.line_number,
+		       lf.file_path,
+		       lf.start_timestamp_utc
+		FROM ` + src.prefix + `log_errors le
+		JOIN ` + src.prefix + `log_files lf ON le.log_file_id = lf.id
+		WHERE lf.hutch = ?
+		  AND lf.start_timestamp_utc >= ?
+		  AND lf.start_timestamp_utc < ?
+		  AND NOT (le.error_type = 'slurm' AND le.message LIKE '%CANCELLED%')
+		  AND NOT (le.error_type = 'slurm' AND le.message LIKE '%Job step aborted%')
+	`
+	rows, err := s.db.QueryContext(ctx, query, hutch, utcStart, utcEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var errors []Error
+	for rows.Next() {
+		var e Error
+		var fileTimestamp string
+		if err := rows.Scan(
+			&e.ID, &e.Timestamp, &e.Component, &e.Host,
+			&e.LogLevel, &e.ErrorType, &e.Message, &e.LineNumber,
+			&e.FilePath, &fileTimestamp,
+		); err != nil {
+			return nil, err
+		}
+
+		// Set DateRef for timezone conversion (use the Pacific date we're querying)
+		e.ID = globalID(i, e.ID)
+		e.DateRef = pacificDate
+		e.Hutch = hutch
+		e.Source = s.sourceLabel(i)
+
+		// Verify this error actually falls on the target Pacific date
+		// (handles edge cases near midnight)
+		errPacificDate := UTCTimestampToPacificDate(fileTimestamp)
+		if errPacificDate == pacificDate {
+			// Note: e.Timestamp stays as stored. ResolveTime falls back to the
+			// filename, which is already Pacific. See Timezone Conventions.
+			e.FileStartUTC, _ = ParseUTCTimestamp(fileTimestamp)
+			e.ResolveTime()
+			errors = append(errors, e)
+		}
+	}
+	return errors, rows.Err()
+}
+
+// contextBatch is the most IDs bound in one Contexts query, well under
+// SQLite's host parameter limit
+const contextBatch = 500
+
+// Contexts loads context lines for errors by ID
+func (s *SQLiteStore) Contexts(ctx context.Context, ids []int) (map[int]ErrorContext, error) {
+	bySource := make(map[int][]int)
+	for _, id := range ids {
+		src, local := localID(id)
+		if src >= 0 && src < len(s.sources) {
+			bySource[src] = append(bySource[src], local)
+		}
+	}
+
+	contexts := make(map[int]ErrorContext, len(ids))
+	for i, locals := range bySource {
+		if err := s.sourceContexts(ctx, i, locals, contexts); err != nil {
+			return nil, err
+		}
+	}
+	return contexts, nil
+}
+
+// sourceContexts loads one database's context lines into contexts
+func (s *SQLiteStore) sourceContexts(ctx context.Context, i int, ids []int, contexts map[int]ErrorContext) error {
+	src := s.sources[i]
+	for start := 0; start < len(ids); start += contextBatch {
+		batch := ids[start:min(start+contextBatch, len(ids))]
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		query := `
+			SELECT le.id,
+			       COALESCE(` + src.schema.ContextBefore + `, ''),
+			       COALESCE(` + src.schema.ContextAfter + `, '')
+			FROM ` + src.prefix + `log_errors le
+			WHERE le.id IN (?` + strings.Repeat(",?", len(batch)-1) + `)
+		`
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int
+			var c ErrorContext
+			if err := rows.Scan(&id, &c.Before, &c.After); err != nil {
+				rows.Close()
+				return err
+			}
+			contexts[globalID(i, id)] = c
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrorByID loads a single error with its context
+func (s *SQLiteStore) ErrorByID(ctx context.Context, id int) (*Error, error) {
+	i, local := localID(id)
+	if id < 0 || i >= len(s.sources) {
+		return nil, fmt.Errorf("error %d: %w", id, ErrNotFound)
+	}
+	src := s.sources[i]
+
+	query := `
+		SELECT le.id,
+		       lf.hutch,
+		       COALESCE(` + src.schema.ErrorTimestamp + `, '') as timestamp,
+		       lf.component,
+		       lf.host,
+		       le.log_level,
+		       le.error_type,
+		       le.message,
+		       le.line_number,
+		       lf.file_path,
+		       COALESCE(` + src.schema.ContextBefore + `, '') as ctx_before,
+		       COALESCE(` + src.schema.ContextAfter + `, '') as ctx_after,
+		       lf.start_timestamp_utc
+		FROM ` + src.prefix + `log_errors le
+		JOIN ` + src.prefix + `log_files lf ON le.log_file_id = lf.id
+		WHERE le.id = ?
+	`
+	var e Error
+	var fileTimestamp string
+	err := s.db.QueryRowContext(ctx, query, local).Scan(
+		&e.ID, &e.Hutch, &e.Timestamp, &e.Component, &e.Host,
+		&e.LogLevel, &e.ErrorType, &e.Message, &e.LineNumber,
+		&e.FilePath, &e.ContextBefore, &e.ContextAfter, &fileTimestamp,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("error %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Same date attribution as Errors: the Pacific date the file started on
+	e.ID = id
+	e.Source = s.sourceLabel(i)
+	e.DateRef = UTCTimestampToPacificDate(fileTimestamp)
+	e.FileStartUTC, _ = ParseUTCTimestamp(fileTimestamp)
+	e.ResolveTime()
+	return &e, nil
+}
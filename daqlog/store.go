@@ -0,0 +1,135 @@
+// Package daqlog reads DAQ errors ingested from LCLS log files. It provides the
+// Store interface with SQLite and in-memory implementations, and the helpers for
+// converting between the UTC times in the database and Pacific display times.
+package daqlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDates is the most dates Store.Dates returns
+const maxDates = 60
+
+// Error represents a single error from the database. Lists returned by
+// Store.Errors leave ContextBefore and ContextAfter empty; see Store.Contexts.
+type Error struct {
+	ID            int
+	Hutch         string
+	Timestamp     string
+	Component     string
+	Host          string
+	LogLevel      string
+	ErrorType     string
+	Message       string
+	LineNumber    int
+	FilePath      string
+	ContextBefore string
+	ContextAfter  string
+	DateRef       string // Reference date (Pacific) for timezone conversion
+	Source        string // Database file the error was read from, when a store reads several
+
+	// Resolved once by ResolveTime when the error is loaded
+	TimeUTC      time.Time  // When the error occurred; zero if unknown
+	TimePacific  time.Time  // TimeUTC in PacificLoc
+	TimeSource   TimeSource // Where the time came from
+	FileStartUTC time.Time  // Start of the log file, used to order ties
+}
+
+// ErrorContext is the log text around an error
+type ErrorContext struct {
+	Before string
+	After  string
+}
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = fmt.Errorf("not found")
+
+// DateSummary represents a date with error counts
+type DateSummary struct {
+	Date       string
+	FileCount  int
+	ErrorCount int
+}
+
+// HutchSummary represents a hutch with error counts
+type HutchSummary struct {
+	Hutch      string
+	FileCount  int
+	ErrorCount int
+}
+
+// Store gives read access to ingested errors. Every method stops early and
+// returns ctx.Err() once ctx is cancelled.
+type Store interface {
+	// Hutches returns hutches that have errors, sorted alphabetically
+	Hutches(ctx context.Context) ([]HutchSummary, error)
+
+	// Dates returns the Pacific dates with errors for a hutch, latest first
+	// (at most 60)
+	Dates(ctx context.Context, hutch string) ([]DateSummary, error)
+
+	// Errors returns a hutch's errors for a Pacific date in chronological order,
+	// without slurm cancellation noise. Context lines are not loaded.
+	Errors(ctx context.Context, hutch, pacificDate string) ([]Error, error)
+
+	// Contexts returns the context lines of errors by ID. IDs that do not
+	// exist are left out of the map.
+	Contexts(ctx context.Context, ids []int) (map[int]ErrorContext, error)
+
+	// ErrorByID returns a single error with its context. Hutch and DateRef are
+	// resolved from the log file, with DateRef being the file's Pacific start
+	// date. A missing error wraps ErrNotFound.
+	ErrorByID(ctx context.Context, id int) (*Error, error)
+}
+
+// FillContexts loads the context lines of errors in place
+func FillContexts(ctx context.Context, store Store, errors []Error) error {
+	ids := make([]int, len(errors))
+	for i, e := range errors {
+		ids[i] = e.ID
+	}
+	contexts, err := store.Contexts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range errors {
+		c := contexts[errors[i].ID]
+		errors[i].ContextBefore = c.Before
+		errors[i].ContextAfter = c.After
+	}
+	return nil
+}
+
+// isNoise reports whether an error is slurm cancellation noise, which Errors
+// skips. Matching is case-insensitive like SQL LIKE.
+func isNoise(e Error) bool {
+	msg := strings.ToLower(e.Message)
+	return e.ErrorType == "slurm" &&
+		(strings.Contains(msg, "cancelled") || strings.Contains(msg, "job step aborted"))
+}
+
+// sortErrors orders resolved errors chronologically, with unknown times last.
+// Ties go by log file start time, then file path and line number, so the order
+// is the same on every load.
+func sortErrors(errors []Error) {
+	sort.SliceStable(errors, func(i, j int) bool {
+		a, b := &errors[i], &errors[j]
+		if ua, ub := a.TimeSource == TimeUnknown, b.TimeSource == TimeUnknown; ua != ub {
+			return ub
+		}
+		if !a.TimeUTC.Equal(b.TimeUTC) {
+			return a.TimeUTC.Before(b.TimeUTC)
+		}
+		if !a.FileStartUTC.Equal(b.FileStartUTC) {
+			return a.FileStartUTC.Before(b.FileStartUTC)
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.LineNumber < b.LineNumber
+	})
+}
@@ -0,0 +1,274 @@
+package daqlog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testFile is a log file and its errors, written to both stores
+type testFile struct {
+	hutch, path, startUTC string
+	errors                []testError
+}
+
+type testError struct {
+	id        int
+	timestamp string
+	level     string
+	errType   string
+	message   string
+}
+
+// testFiles has a tmo file started just before Pacific midnight, one after it
+// holding slurm cancellation noise, and a rix file with a slurm error that is
+// not noise
+var testFiles = []testFile{
+	{"tmo", "/logs/tmo/2025/11/18_23:50:00_drp-srcf-cmp002:teb0.log", "2025-11-19 07:50:00", []testError{
+		{5, "2025-11-19 07:55:00", "E", "timeout", "Event builder timeout: 3 contributions missing"},
+	}},
+	{"tmo", "/logs/tmo/2025/11/19_00:30:00_drp-srcf-cmp001:drp1.log", "2025-11-19 08:30:00", []testError{
+		{1, "2025-11-19 08:31:00", "E", "timeout", "Timeout waiting for PV TMO:DAQ:1"},
+		{2, "", "C", "exception", "std::runtime_error: DMA buffer 3 overflow"},
+		{3, "2025-11-19 08:32:00", "E", "slurm", "slurmstepd: error: *** STEP 1.0 ON drp-srcf-cmp001 CANCELLED AT 2025-11-19T08:32:00 ***"},
+		{4, "2025-11-19 08:33:00", "E", "slurm", "srun: Job step aborted: Waiting up to 32 seconds for job step to finish."},
+	}},
+	{"rix", "/logs/rix/2025/11/19_02:00:00_drp-neh-cmp001:xpm.log", "2025-11-19 10:00:00", []testError{
+		{6, "2025-11-19 10:01:00", "E", "slurm", "srun: error: drp-neh-cmp001: task 1: Exited with exit code 1"},
+	}},
+}
+
+// openTestStores writes testFiles to a SQLite database and a MemoryStore
+func openTestStores(t *testing.T) (*SQLiteStore, *MemoryStore) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "daq_logs.db")
+	mem := writeTestDB(t, path)
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, NewMemoryStore(mem)
+}
+
+// writeTestDB writes testFiles to a new database at path and returns the same
+// errors for a MemoryStore
+func writeTestDB(t *testing.T, path string) []Error {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE log_files (id INTEGER PRIMARY KEY, file_path TEXT, hutch TEXT, host TEXT,
+			component TEXT, start_timestamp_utc TEXT, error_count INTEGER);
+		CREATE TABLE log_errors (id INTEGER PRIMARY KEY, log_file_id INTEGER, line_number INTEGER,
+			timestamp_utc TEXT, log_level TEXT, error_type TEXT, message TEXT,
+			context_before TEXT, context_after TEXT);
+		CREATE TABLE metadata (key TEXT PRIMARY KEY, value TEXT);
+		INSERT INTO metadata VALUES ('schema_version', '2');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	var mem []Error
+	for fi, f := range testFiles {
+		host, component := "drp-srcf-cmp001", filepath.Base(f.path)
+		if _, err := db.Exec(`INSERT INTO log_files VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			fi+1, f.path, f.hutch, host, component, f.startUTC, len(f.errors)); err != nil {
+			t.Fatal(err)
+		}
+		start, _ := ParseUTCTimestamp(f.startUTC)
+		for i, e := range f.errors {
+			before := fmt.Sprintf("context of %d", e.id)
+			if _, err := db.Exec(`INSERT INTO log_errors VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, '')`,
+				e.id, fi+1, 100+i, e.timestamp, e.level, e.errType, e.message, before); err != nil {
+				t.Fatal(err)
+			}
+			mem = append(mem, Error{
+				ID: e.id, Hutch: f.hutch, Timestamp: e.timestamp, Component: component, Host: host,
+				LogLevel: e.level, ErrorType: e.errType, Message: e.message, LineNumber: 100 + i,
+				FilePath: f.path, ContextBefore: before, DateRef: UTCTimestampToPacificDate(f.startUTC),
+				FileStartUTC: start,
+			})
+		}
+	}
+	db.Close()
+	return mem
+}
+
+// errorIDs lists the IDs of errors in order
+func errorIDs(errors []Error) []int {
+	ids := []int{}
+	for _, e := range errors {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestStoresAgree(t *testing.T) {
+	sqlite, mem := openTestStores(t)
+	ctx := context.Background()
+
+	for _, store := range []Store{sqlite, mem} {
+		name := fmt.Sprintf("%T", store)
+
+		hutches, err := store.Hutches(ctx)
+		if err != nil {
+			t.Fatalf("%s: Hutches: %v", name, err)
+		}
+		want := []HutchSummary{{"rix", 1, 1}, {"tmo", 2, 5}}
+		if !reflect.DeepEqual(hutches, want) {
+			t.Errorf("%s: Hutches = %v, want %v", name, hutches, want)
+		}
+
+		dates, err := store.Dates(ctx, "tmo")
+		if err != nil {
+			t.Fatalf("%s: Dates: %v", name, err)
+		}
+		wantDates := []DateSummary{{"2025-11-19", 1, 4}, {"2025-11-18", 1, 1}}
+		if !reflect.DeepEqual(dates, wantDates) {
+			t.Errorf("%s: Dates = %v, want %v", name, dates, wantDates)
+		}
+
+		for _, tt := range []struct {
+			hutch, date string
+			want        []int
+		}{
+			// 2 has no timestamp and takes 00:30 Pacific from its filename; 3
+			// and 4 are noise
+			{"tmo", "2025-11-19", []int{2, 1}},
+			{"tmo", "2025-11-18", []int{5}},
+			{"rix", "2025-11-19", []int{6}},
+			{"rix", "2025-11-18", []int{}},
+		} {
+			found, err := store.Errors(ctx, tt.hutch, tt.date)
+			if err != nil {
+				t.Fatalf("%s: Errors(%s, %s): %v", name, tt.hutch, tt.date, err)
+			}
+			if got := errorIDs(found); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: Errors(%s, %s) = %v, want %v", name, tt.hutch, tt.date, got, tt.want)
+			}
+			for _, e := range found {
+				if e.ContextBefore != "" || e.DateRef != tt.date || e.Hutch != tt.hutch {
+					t.Errorf("%s: error %d has context %q, date %s, hutch %s", name, e.ID, e.ContextBefore, e.DateRef, e.Hutch)
+				}
+			}
+		}
+
+		if _, err := store.Errors(ctx, "tmo", "19/11/2025"); err == nil {
+			t.Errorf("%s: no error for an invalid date", name)
+		}
+	}
+}
+
+func TestStoresErrorByID(t *testing.T) {
+	sqlite, mem := openTestStores(t)
+	ctx := context.Background()
+
+	// Fields both stores fill in for a single error
+	type summary struct {
+		ID                  int
+		Hutch, DateRef      string
+		Message, Context    string
+		TimeUTC, TimeSource string
+	}
+	summarize := func(e *Error) summary {
+		return summary{e.ID, e.Hutch, e.DateRef, e.Message, e.ContextBefore,
+			e.TimeUTC.Format("2006-01-02 15:04:05"), e.TimeSource.String()}
+	}
+
+	for _, id := range []int{1, 2, 3, 5} {
+		a, err := sqlite.ErrorByID(ctx, id)
+		if err != nil {
+			t.Fatalf("SQLite: ErrorByID(%d): %v", id, err)
+		}
+		b, err := mem.ErrorByID(ctx, id)
+		if err != nil {
+			t.Fatalf("memory: ErrorByID(%d): %v", id, err)
+		}
+		if sa, sb := summarize(a), summarize(b); sa != sb {
+			t.Errorf("ErrorByID(%d): SQLite %+v, memory %+v", id, sa, sb)
+		}
+	}
+
+	got, _ := sqlite.ErrorByID(ctx, 5)
+	if got.DateRef != "2025-11-18" || got.TimeSource != TimeFromDB {
+		t.Errorf("error 5: date %s, time from %s; want 2025-11-18 from db", got.DateRef, got.TimeSource)
+	}
+
+	for _, store := range []Store{sqlite, mem} {
+		if _, err := store.ErrorByID(ctx, 99); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%T: ErrorByID(99) = %v, want ErrNotFound", store, err)
+		}
+	}
+}
+
+func TestSortErrors(t *testing.T) {
+	at := func(hh, mm int) time.Time { return time.Date(2025, 11, 19, hh, mm, 0, 0, time.UTC) }
+	mk := func(id int, when time.Time, fileStart time.Time, path string, line int) Error {
+		src := TimeFromDB
+		if when.IsZero() {
+			src = TimeUnknown
+		}
+		return Error{ID: id, TimeUTC: when, TimeSource: src, FileStartUTC: fileStart, FilePath: path, LineNumber: line}
+	}
+
+	list := []Error{
+		mk(1, time.Time{}, at(8, 0), "/a.log", 1), // Unknown times go last, tied like the rest
+		mk(2, at(9, 0), at(8, 30), "/b.log", 5),
+		mk(3, at(9, 0), at(8, 0), "/z.log", 9),  // Same time: earlier file start first
+		mk(4, at(9, 0), at(8, 30), "/a.log", 7), // Same file start: path, then line
+		mk(5, at(9, 0), at(8, 30), "/a.log", 3),
+		mk(6, time.Time{}, at(7, 0), "/a.log", 1),
+		mk(7, at(8, 59), at(9, 0), "/z.log", 1),
+	}
+	sortErrors(list)
+	if got, want := errorIDs(list), []int{7, 3, 5, 4, 2, 6, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestOpenSQLiteEscapesPaths(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "odd dir")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// "%41" would read as "A" and "?" or "#" would end the path if not escaped
+	paths := []string{filepath.Join(dir, "a?b#c%41.db"), filepath.Join(dir, "x%y.db")}
+	for _, path := range paths {
+		writeTestDB(t, path)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*")); len(matches) != 2 {
+		t.Fatalf("created %v, want only the two test databases", matches)
+	}
+
+	store, err := OpenSQLite(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if got := store.Files(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("Files() = %v, want %v", got, paths)
+	}
+	found, err := store.Errors(context.Background(), "tmo", "2025-11-19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second database repeats the first one's lines, which are read once
+	if got := errorIDs(found); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("Errors = %v, want [2 1]", got)
+	}
+	if found[0].Source != paths[0] {
+		t.Errorf("Source = %q, want %q", found[0].Source, paths[0])
+	}
+}
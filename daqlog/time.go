@@ -0,0 +1,262 @@
+package daqlog
+
+import (
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Timezone Conventions
+// =============================================================================
+//
+// This codebase handles three different timestamp sources with different timezones:
+//
+// 1. LOG FILENAMES: Times are in PACIFIC TIME (America/Los_Angeles)
+//    Format: DD_HH:MM:SS_host:component.log (e.g., 02_08:00:57_rix-daq:xpmpva.log)
+//    The HH:MM:SS is the local Pacific time when the DAQ process started.
+//
+// 2. DATABASE (start_timestamp_utc, timestamp_utc): Times are in UTC
+//    The ingestion script (ingest_daq_logs.py) converts Pacific → UTC before storing.
+//
+// 3. DISPLAY: Times are shown in PACIFIC TIME
+//    The browser converts UTC timestamps back to Pacific for display.
+//
+// IMPORTANT: When extracting time from filenames for display, do NOT convert
+// (it's already Pacific). Only convert times read from database fields.
+// =============================================================================
+
+// PacificLoc is the Pacific timezone for LCLS (handles DST automatically)
+var PacificLoc *time.Location
+
+func init() {
+	var err error
+	PacificLoc, err = time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		// Fallback to fixed PST offset if timezone data unavailable
+		PacificLoc = time.FixedZone("PST", -8*60*60)
+	}
+}
+
+// UTCToPacific converts a UTC time to Pacific time
+func UTCToPacific(t time.Time) time.Time {
+	return t.In(PacificLoc)
+}
+
+// LoadZone resolves a display timezone: "pacific", "utc", "local" or any IANA
+// name such as "Europe/Paris"
+func LoadZone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "pacific":
+		return PacificLoc, nil
+	case "utc":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// IsRepeatedWallTime reports whether t's wall-clock time in loc also occurs at
+// another instant, as in the hour repeated when daylight saving time ends
+func IsRepeatedWallTime(t time.Time, loc *time.Location) bool {
+	local := t.In(loc)
+	_, offset := local.Zone()
+	wall := local.Format("2006-01-02 15:04:05")
+	for _, probe := range []time.Time{t.Add(-3 * time.Hour), t.Add(3 * time.Hour)} {
+		_, other := probe.In(loc).Zone()
+		if other == offset {
+			continue
+		}
+		alt := t.Add(time.Duration(offset-other) * time.Second).In(loc)
+		if _, altOffset := alt.Zone(); altOffset == other && alt.Format("2006-01-02 15:04:05") == wall {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatClock formats t in loc, appending the UTC offset (e.g. "01:30 -07")
+// when the wall time is repeated so the two occurrences stay distinct
+func FormatClock(t time.Time, loc *time.Location, layout string) string {
+	s := t.In(loc).Format(layout)
+	if IsRepeatedWallTime(t, loc) {
+		s += t.In(loc).Format(" -07")
+	}
+	return s
+}
+
+// UTCTimestampToPacificDate converts a UTC timestamp string to a Pacific date string (YYYY-MM-DD)
+func UTCTimestampToPacificDate(timestamp string) string {
+	if timestamp == "" {
+		return ""
+	}
+
+	// Try common timestamp formats
+	for _, layout := range []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04:05Z",
+	} {
+		if t, err := time.Parse(layout, timestamp); err == nil {
+			t = t.UTC()
+			pacific := UTCToPacific(t)
+			return pacific.Format("2006-01-02")
+		}
+	}
+
+	// If just a date, convert as if midnight UTC
+	if len(timestamp) == 10 {
+		if t, err := time.Parse("2006-01-02", timestamp); err == nil {
+			t = t.UTC()
+			pacific := UTCToPacific(t)
+			return pacific.Format("2006-01-02")
+		}
+	}
+
+	return ""
+}
+
+// PacificDateToUTCRange returns the UTC time range for a Pacific date
+// Returns start (inclusive) and end (exclusive) timestamps
+func PacificDateToUTCRange(pacificDate string) (string, string, error) {
+	// Parse the date in Pacific timezone
+	dateParsed, err := time.ParseInLocation("2006-01-02", pacificDate, PacificLoc)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Start of day in Pacific (midnight)
+	startPacific := dateParsed
+
+	// End of day in Pacific (next day midnight)
+	endPacific := startPacific.AddDate(0, 0, 1)
+
+	// Convert to UTC
+	startUTC := startPacific.UTC().Format("2006-01-02 15:04:05")
+	endUTC := endPacific.UTC().Format("2006-01-02 15:04:05")
+
+	return startUTC, endUTC, nil
+}
+
+// TimeSource records where an error's time came from
+type TimeSource int
+
+const (
+	TimeUnresolved   TimeSource = iota // ResolveTime has not run
+	TimeUnknown                        // No usable time anywhere
+	TimeFromDB                         // Full UTC timestamp from the database
+	TimeFromFilename                   // Pacific HH:MM:SS in the log filename
+	TimeInferred                       // Time-only timestamp or file start time, anchored to DateRef
+)
+
+func (s TimeSource) String() string {
+	switch s {
+	case TimeFromDB:
+		return "db"
+	case TimeFromFilename:
+		return "filename"
+	case TimeInferred:
+		return "inferred"
+	case TimeUnknown:
+		return "unknown"
+	}
+	return "unresolved"
+}
+
+// timestampLayouts are the full UTC timestamp formats found in the database
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z",
+}
+
+// ParseUTCTimestamp parses a full database timestamp, in any of the layouts
+// the ingester has written, as UTC
+func ParseUTCTimestamp(timestamp string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, timestamp); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ResolveTime sets TimeUTC, TimePacific and TimeSource from the raw Timestamp,
+// FilePath, DateRef and FileStartUTC. Stores call it once at load, so nothing
+// downstream re-parses strings. In order of preference:
+//
+//  1. A full database timestamp (UTC)
+//  2. A time-only database timestamp, read as UTC on DateRef (inferred)
+//  3. The filename's HH:MM:SS, already Pacific, on DateRef
+//  4. The log file's start time (inferred)
+func (e *Error) ResolveTime() {
+	e.TimeUTC, e.TimeSource = time.Time{}, TimeUnknown
+
+	if t, ok := ParseUTCTimestamp(e.Timestamp); ok {
+		e.TimeUTC, e.TimeSource = t, TimeFromDB
+	} else if t, ok := parseTimeOnUTCDate(e.Timestamp, e.DateRef); ok {
+		e.TimeUTC, e.TimeSource = t, TimeInferred
+	} else if clock := ExtractTimeFromPath(e.FilePath); clock != "" && e.DateRef != "" {
+		// Already Pacific: do not convert (see Timezone Conventions above)
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", e.DateRef+" "+clock, PacificLoc); err == nil {
+			e.TimeUTC, e.TimeSource = t.UTC(), TimeFromFilename
+		}
+	}
+	if e.TimeSource == TimeUnknown && !e.FileStartUTC.IsZero() {
+		e.TimeUTC, e.TimeSource = e.FileStartUTC, TimeInferred
+	}
+
+	e.TimePacific = time.Time{}
+	if e.TimeSource != TimeUnknown {
+		e.TimePacific = UTCToPacific(e.TimeUTC)
+	}
+}
+
+// parseTimeOnUTCDate reads an "HH:MM[:SS]" UTC time on a reference date
+func parseTimeOnUTCDate(clock, dateRef string) (time.Time, bool) {
+	if dateRef == "" || len(clock) < 5 || clock[2] != ':' {
+		return time.Time{}, false
+	}
+	if len(clock) == 5 {
+		clock += ":00"
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", dateRef+" "+clock[:min(len(clock), 8)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ErrorTime returns the instant an error occurred, in UTC, resolving it first
+// for errors that did not come from a Store
+func ErrorTime(e Error) (time.Time, bool) {
+	if e.TimeSource == TimeUnresolved {
+		e.ResolveTime()
+	}
+	return e.TimeUTC, e.TimeSource != TimeUnknown
+}
+
+// ErrorClock returns the error's Pacific time formatted with layout, or "" when
+// unknown
+func ErrorClock(e Error, layout string) string {
+	t, ok := ErrorTime(e)
+	if !ok {
+		return ""
+	}
+	return UTCToPacific(t).Format(layout)
+}
+
+// ExtractTimeFromPath extracts HH:MM:SS from path like .../DD_HH:MM:SS_host:component.log
+func ExtractTimeFromPath(path string) string {
+	// Look for pattern DD_HH:MM:SS
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 {
+		return ""
+	}
+	filename := parts[len(parts)-1]
+	// Format: DD_HH:MM:SS_host:component.log
+	if len(filename) > 11 && filename[2] == '_' && filename[5] == ':' && filename[8] == ':' {
+		return filename[3:11] // HH:MM:SS
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package daqlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacificDateToUTCRange(t *testing.T) {
+	for _, tt := range []struct {
+		date       string
+		start, end string
+	}{
+		{"2025-11-19", "2025-11-19 08:00:00", "2025-11-20 08:00:00"},
+		{"2025-07-01", "2025-07-01 07:00:00", "2025-07-02 07:00:00"},
+		{"2025-03-09", "2025-03-09 08:00:00", "2025-03-10 07:00:00"}, // 23h: DST starts
+		{"2025-11-02", "2025-11-02 07:00:00", "2025-11-03 08:00:00"}, // 25h: DST ends
+	} {
+		start, end, err := PacificDateToUTCRange(tt.date)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.date, err)
+		}
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s: [%s, %s), want [%s, %s)", tt.date, start, end, tt.start, tt.end)
+		}
+	}
+
+	if _, _, err := PacificDateToUTCRange("2025-11-31"); err == nil {
+		t.Error("2025-11-31: no error for an invalid date")
+	}
+}
+
+func TestUTCTimestampToPacificDate(t *testing.T) {
+	for _, tt := range []struct {
+		timestamp, want string
+	}{
+		{"2025-11-19 07:59:59", "2025-11-18"},
+		{"2025-11-19 08:00:00", "2025-11-19"},
+		{"2025-11-19T08:00:00Z", "2025-11-19"},
+		{"2025-07-01T06:59:59", "2025-06-30"}, // PDT
+		{"2025-07-01T07:00:00", "2025-07-01"},
+		{"2025-11-19", "2025-11-18"}, // Midnight UTC
+		{"", ""},
+		{"19:00:00", ""},
+	} {
+		if got := UTCTimestampToPacificDate(tt.timestamp); got != tt.want {
+			t.Errorf("UTCTimestampToPacificDate(%q) = %q, want %q", tt.timestamp, got, tt.want)
+		}
+	}
+}
+
+func TestExtractTimeFromPath(t *testing.T) {
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/logs/tmo/2025/11/19_08:00:57_drp-srcf-cmp001:drp1.log", "08:00:57"},
+		{"02_23:59:59_rix-daq:xpmpva.log", "23:59:59"},
+		{"/logs/tmo/2025/11/drp1.log", ""},
+		{"/logs/19_08-00-57_host:drp1.log", ""},
+		{"", ""},
+	} {
+		if got := ExtractTimeFromPath(tt.path); got != tt.want {
+			t.Errorf("ExtractTimeFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTime(t *testing.T) {
+	const path = "/logs/tmo/2025/11/19_00:30:00_drp-srcf-cmp001:drp1.log"
+	fileStart := time.Date(2025, 11, 19, 8, 29, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name      string
+		e         Error
+		wantUTC   string // Zero time when unknown
+		wantSrc   TimeSource
+		wantClock string // Pacific HH:MM:SS
+	}{
+		{"db timestamp",
+			Error{Timestamp: "2025-11-19 16:05:07", FilePath: path, DateRef: "2025-11-19", FileStartUTC: fileStart},
+			"2025-11-19 16:05:07", TimeFromDB, "08:05:07"},
+		{"db timestamp with T and Z",
+			Error{Timestamp: "2025-11-19T16:05:07Z"},
+			"2025-11-19 16:05:07", TimeFromDB, "08:05:07"},
+		{"time only, read as UTC on DateRef",
+			Error{Timestamp: "16:05:07.250", FilePath: path, DateRef: "2025-11-19", FileStartUTC: fileStart},
+			"2025-11-19 16:05:07", TimeInferred, "08:05:07"},
+		{"time only without seconds",
+			Error{Timestamp: "16:05", DateRef: "2025-11-19"},
+			"2025-11-19 16:05:00", TimeInferred, "08:05:00"},
+		{"filename, already Pacific",
+			Error{FilePath: path, DateRef: "2025-11-19", FileStartUTC: fileStart},
+			"2025-11-19 08:30:00", TimeFromFilename, "00:30:00"},
+		{"filename without DateRef falls back to file start",
+			Error{Timestamp: "16:05:07", FilePath: path, FileStartUTC: fileStart},
+			"2025-11-19 08:29:00", TimeInferred, "00:29:00"},
+		{"file start",
+			Error{Timestamp: "garbage", FilePath: "/logs/drp1.log", DateRef: "2025-11-19", FileStartUTC: fileStart},
+			"2025-11-19 08:29:00", TimeInferred, "00:29:00"},
+		{"nothing usable",
+			Error{FilePath: "/logs/drp1.log", DateRef: "2025-11-19"},
+			"0001-01-01 00:00:00", TimeUnknown, ""},
+	} {
+		e := tt.e
+		e.ResolveTime()
+		if got := e.TimeUTC.Format("2006-01-02 15:04:05"); got != tt.wantUTC || e.TimeSource != tt.wantSrc {
+			t.Errorf("%s: %s from %s, want %s from %s", tt.name, got, e.TimeSource, tt.wantUTC, tt.wantSrc)
+		}
+		if got := ErrorClock(e, "15:04:05"); got != tt.wantClock {
+			t.Errorf("%s: clock %q, want %q", tt.name, got, tt.wantClock)
+		}
+		if tt.wantSrc == TimeUnknown && !e.TimePacific.IsZero() {
+			t.Errorf("%s: TimePacific %v, want zero", tt.name, e.TimePacific)
+		}
+		if tt.wantSrc != TimeUnknown && (e.TimePacific.Location() != PacificLoc || !e.TimePacific.Equal(e.TimeUTC)) {
+			t.Errorf("%s: TimePacific %v is not TimeUTC in Pacific", tt.name, e.TimePacific)
+		}
+	}
+}
+
+func TestRepeatedWallTime(t *testing.T) {
+	// 08:30Z is 01:30 PDT and 09:30Z is 01:30 PST on 2025-11-02
+	first := time.Date(2025, 11, 2, 8, 30, 0, 0, time.UTC)
+	second := time.Date(2025, 11, 2, 9, 30, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{first, PacificLoc, "01:30 -07"},
+		{second, PacificLoc, "01:30 -08"},
+		{time.Date(2025, 11, 2, 11, 30, 0, 0, time.UTC), PacificLoc, "03:30"}, // After the change
+		{time.Date(2025, 11, 2, 7, 30, 0, 0, time.UTC), PacificLoc, "00:30"},  // Before it
+		{time.Date(2025, 3, 9, 10, 30, 0, 0, time.UTC), PacificLoc, "03:30"},  // DST start skips an hour instead
+		{time.Date(2025, 11, 19, 9, 30, 0, 0, time.UTC), PacificLoc, "01:30"}, // Ordinary night
+		{first, time.UTC, "08:30"},
+	} {
+		if got := FormatClock(tt.t, tt.loc, "15:04"); got != tt.want {
+			t.Errorf("FormatClock(%s, %s) = %q, want %q", tt.t.Format(time.RFC3339), tt.loc, got, tt.want)
+		}
+		if got, want := IsRepeatedWallTime(tt.t, tt.loc), len(tt.want) > 5; got != want {
+			t.Errorf("IsRepeatedWallTime(%s, %s) = %v, want %v", tt.t.Format(time.RFC3339), tt.loc, got, want)
+		}
+	}
+}
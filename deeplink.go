@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// linkScheme prefixes deep links such as daq://tmo/2025-11-19/error/123
+const linkScheme = "daq://"
+
+// deepLink returns the daq:// link to a view: the hutch, the date and the
+// selected error, as far as they are known
+func deepLink(v viewState) string {
+	link := linkScheme + v.Hutch
+	if v.Date != "" {
+		link += "/" + v.Date
+		if v.ErrorID > 0 {
+			link += "/error/" + strconv.Itoa(v.ErrorID)
+		}
+	}
+	return link
+}
+
+// parseDeepLink reads a daq://hutch[/date[/error/ID]] link into a view state
+func parseDeepLink(link string) (viewState, error) {
+	v := viewState{Mode: ModeHutchPicker}
+	rest, ok := strings.CutPrefix(link, linkScheme)
+	if !ok {
+		return v, fmt.Errorf("%q is not a %s link", link, linkScheme)
+	}
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	bad := fmt.Errorf("invalid link %q (want %shutch/YYYY-MM-DD/error/ID)", link, linkScheme)
+	if parts[0] == "" || len(parts) > 4 || len(parts) == 3 {
+		return v, bad
+	}
+	v.Hutch = parts[0]
+	if len(parts) >= 2 {
+		if _, err := time.Parse("2006-01-02", parts[1]); err != nil {
+			return v, bad
+		}
+		v.Date = parts[1]
+	}
+	if len(parts) == 4 {
+		id, err := strconv.Atoi(parts[3])
+		if parts[2] != "error" || err != nil || id <= 0 {
+			return v, bad
+		}
+		v.ErrorID = id
+	}
+	return v, nil
+}
+
+// shellCommand returns a command line running this program that reopens a
+// view, with only the flags that differ from the defaults
+func shellCommand(v viewState, dbFiles []string, loc *time.Location) string {
+	args := []string{filepath.Base(os.Args[0])}
+	add := func(flag, value string) {
+		args = append(args, flag, shellQuote(value))
+	}
+	if len(dbFiles) > 0 {
+		add("--db", strings.Join(dbFiles, ","))
+	}
+
+	switch {
+	case v.ErrorID > 0:
+		// The error fixes the hutch and date
+		add("--error-id", strconv.Itoa(v.ErrorID))
+	case v.Hutch != "":
+		add("--hutch", v.Hutch)
+		if v.Date != "" {
+			add("--date", v.Date)
+		}
+	}
+
+	if v.LevelFilter == "C" {
+		args = append(args, "--critical")
+	}
+	if v.ComponentFilter != "" {
+		add("--component", v.ComponentFilter)
+	}
+	if v.MessageFilter != "" {
+		add("--message", v.MessageFilter)
+	}
+	if v.GroupKey != GroupByComponent {
+		add("--group-by", v.GroupKey.String())
+	}
+	if v.TimeBucket != Bucket1m {
+		add("--bucket", bucketFlagName(v.TimeBucket))
+	}
+	if v.GroupSort != SortGroupsByTime {
+		add("--group-sort", v.GroupSort.String())
+	}
+	if v.ErrorSort != SortErrorsByTime {
+		add("--error-sort", v.ErrorSort.String())
+	}
+	if v.ShowIncidents {
+		args = append(args, "--incidents")
+	}
+	if v.IncidentGap != 0 && v.IncidentGap != defaultIncidentGap {
+		add("--incident-gap", v.IncidentGap.String())
+	}
+	switch loc {
+	case daqlog.PacificLoc:
+	case time.UTC:
+		add("--tz", "utc")
+	case time.Local:
+		add("--tz", "local")
+	default:
+		add("--tz", loc.String())
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote single-quotes s for a POSIX shell when it contains anything
+// other than plain word characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./,:=+@", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// namedOption is a setting cycled by a key, whose String is also its flag value
+type namedOption interface {
+	~int
+	String() string
+}
+
+// parseOption returns the option of type T (numbered 0 to n-1) named name
+func parseOption[T namedOption](name string, n T) (T, error) {
+	var names []string
+	for o := T(0); o < n; o++ {
+		if strings.EqualFold(o.String(), name) {
+			return o, nil
+		}
+		names = append(names, o.String())
+	}
+	return 0, fmt.Errorf("unknown value %q (want one of: %s)", name, strings.Join(names, ", "))
+}
+
+// parseTimeBucket reads a --bucket value; "all" stands for "all day"
+func parseTimeBucket(name string) (TimeBucket, error) {
+	var names []string
+	for b := TimeBucket(0); b < numTimeBuckets; b++ {
+		if strings.EqualFold(bucketFlagName(b), name) || strings.EqualFold(b.String(), name) {
+			return b, nil
+		}
+		names = append(names, bucketFlagName(b))
+	}
+	return 0, fmt.Errorf("unknown value %q (want one of: %s)", name, strings.Join(names, ", "))
+}
+
+// bucketFlagName returns the --bucket value for a time bucket
+func bucketFlagName(b TimeBucket) string {
+	if b == BucketNone {
+		return "all"
+	}
+	return b.String()
+}
+
+// updateCopyKey copies a link or command line for the current view. handled is
+// false for other keys.
+func (m Model) updateCopyKey(msg tea.KeyMsg) (model Model, cmd tea.Cmd, handled bool) {
+	var text string
+	switch {
+	case key.Matches(msg, m.keys.CopyLink):
+		text = deepLink(m.captureView())
+	case key.Matches(msg, m.keys.CopyCommand):
+		var files []string
+		if s, ok := m.store.(interface{ Files() []string }); ok {
+			files = s.Files()
+		}
+		text = shellCommand(m.captureView(), files, m.displayLoc)
+	default:
+		return m, nil, false
+	}
+	m.loadNote = "Copied: " + text
+	return m, copyToClipboard(text), true
+}
+
+// copyToClipboard sets the terminal's clipboard with an OSC 52 escape, which
+// also works over SSH. It is written to stderr so it cannot interleave with
+// the rendered frame on stdout.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+			seq = seq.Screen()
+		}
+		seq.WriteTo(os.Stderr)
+		return nil
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Longest date range accepted by the diff, to keep loads bounded
+const maxDiffRangeDays = 31
+
+// A signature counts as changed when its daily rate moved by at least this
+// factor and by at least diffMinDelta errors per day
+const (
+	diffRatio    = 2.0
+	diffMinDelta = 3.0
+)
+
+// DiffStatus classifies a signature in the day-to-day diff
+type DiffStatus int
+
+const (
+	DiffNew DiffStatus = iota
+	DiffGone
+	DiffChanged
+	DiffSame
+)
+
+// String returns the label shown in the diff list
+func (s DiffStatus) String() string {
+	switch s {
+	case DiffNew:
+		return "NEW"
+	case DiffGone:
+		return "GONE"
+	case DiffChanged:
+		return "CHANGED"
+	default:
+		return "same"
+	}
+}
+
+// DateRange is an inclusive range of Pacific dates
+type DateRange struct {
+	From string
+	To   string
+}
+
+// String returns "YYYY-MM-DD" or "YYYY-MM-DD..YYYY-MM-DD"
+func (r DateRange) String() string {
+	if r.From == r.To {
+		return r.From
+	}
+	return r.From + ".." + r.To
+}
+
+// Dates returns every date in the range
+func (r DateRange) Dates() []string {
+	from, _ := time.Parse("2006-01-02", r.From)
+	to, _ := time.Parse("2006-01-02", r.To)
+	var dates []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates
+}
+
+// parseDateRange parses "YYYY-MM-DD" or "YYYY-MM-DD..YYYY-MM-DD"
+func parseDateRange(s string) (DateRange, error) {
+	s = strings.TrimSpace(s)
+	from, to, found := strings.Cut(s, "..")
+	if !found {
+		to = from
+	}
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+	fromT, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return DateRange{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", from)
+	}
+	toT, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return DateRange{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", to)
+	}
+	if toT.Before(fromT) {
+		return DateRange{}, fmt.Errorf("range %s..%s ends before it starts", from, to)
+	}
+	if days := int(toT.Sub(fromT).Hours()/24) + 1; days > maxDiffRangeDays {
+		return DateRange{}, fmt.Errorf("range %s..%s is %d days (max %d)", from, to, days, maxDiffRangeDays)
+	}
+	return DateRange{From: from, To: to}, nil
+}
+
+// LoadErrorsRange loads errors for every Pacific date in a range, in date order
+func LoadErrorsRange(ctx context.Context, store daqlog.Store, hutch string, r DateRange) ([]daqlog.Error, error) {
+	var all []daqlog.Error
+	for _, date := range r.Dates() {
+		errors, err := store.Errors(ctx, hutch, date)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, errors...)
+	}
+	return all, nil
+}
+
+// SignatureDiff compares one message signature between a baseline and a target
+type SignatureDiff struct {
+	Signature    string
+	Status       DiffStatus
+	BaseErrors   []daqlog.Error
+	TargetErrors []daqlog.Error
+	BaseRate     float64 // Errors per day in the baseline
+	TargetRate   float64 // Errors per day in the target
+}
+
+// diffSignatures groups both sides by message signature and classifies each one.
+// Rates are per day so ranges of different lengths compare fairly.
+func diffSignatures(base, target []daqlog.Error, baseDays, targetDays int) []SignatureDiff {
+	bySig := make(map[string]*SignatureDiff)
+	get := func(sig string) *SignatureDiff {
+		d, ok := bySig[sig]
+		if !ok {
+			d = &SignatureDiff{Signature: sig}
+			bySig[sig] = d
+		}
+		return d
+	}
+	for _, e := range base {
+		d := get(messageSignature(e.Message))
+		d.BaseErrors = append(d.BaseErrors, e)
+	}
+	for _, e := range target {
+		d := get(messageSignature(e.Message))
+		d.TargetErrors = append(d.TargetErrors, e)
+	}
+
+	var diffs []SignatureDiff
+	for _, d := range bySig {
+		d.BaseRate = float64(len(d.BaseErrors)) / float64(max(baseDays, 1))
+		d.TargetRate = float64(len(d.TargetErrors)) / float64(max(targetDays, 1))
+		switch {
+		case len(d.BaseErrors) == 0:
+			d.Status = DiffNew
+		case len(d.TargetErrors) == 0:
+			d.Status = DiffGone
+		case math.Abs(d.TargetRate-d.BaseRate) >= diffMinDelta &&
+			math.Max(d.TargetRate, d.BaseRate) >= diffRatio*math.Min(d.TargetRate, d.BaseRate):
+			d.Status = DiffChanged
+		default:
+			d.Status = DiffSame
+		}
+		diffs = append(diffs, *d)
+	}
+
+	// New first, then gone, then changed, each by largest count
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Status != diffs[j].Status {
+			return diffs[i].Status < diffs[j].Status
+		}
+		ci := len(diffs[i].BaseErrors) + len(diffs[i].TargetErrors)
+		cj := len(diffs[j].BaseErrors) + len(diffs[j].TargetErrors)
+		if ci != cj {
+			return ci > cj
+		}
+		return diffs[i].Signature < diffs[j].Signature
+	})
+	return diffs
+}
+
+// diffReturn holds the error list state to restore after drilling into a diff
+type diffReturn struct {
+	allErrors    []daqlog.Error
+	selectedDate string
+}
+
+// startDiffInput opens the diff dialog; target is used when only a baseline is given
+func (m *Model) startDiffInput(target string) tea.Cmd {
+	m.inputMode = InputDiff
+	m.diffDefaultTarget = target
+	m.diffErr = ""
+	m.diffInput.SetValue("")
+	m.diffInput.Focus()
+	return textinput.Blink
+}
+
+// openDiff parses "BASE" or "BASE vs TARGET" and loads the diff. Without an
+// explicit target, the current date is compared against the baseline. A parse
+// error is left in diffErr and nothing is loaded.
+func (m *Model) openDiff(input string, defaultTarget string) tea.Cmd {
+	baseStr, targetStr, found := strings.Cut(input, " vs ")
+	if !found {
+		targetStr = defaultTarget
+	}
+
+	base, err := parseDateRange(baseStr)
+	if err != nil {
+		m.diffErr = err.Error()
+		return nil
+	}
+	target, err := parseDateRange(targetStr)
+	if err != nil {
+		m.diffErr = err.Error()
+		return nil
+	}
+	m.diffErr = ""
+
+	store, hutch := m.store, m.selectedHutch
+	return m.startLoad("Loading diff for "+hutch, func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		baseErrors, err := LoadErrorsRange(ctx, store, hutch, base)
+		if err != nil {
+			return nil, err
+		}
+		targetErrors, err := LoadErrorsRange(ctx, store, hutch, target)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			m.diffBase = base
+			m.diffTarget = target
+			m.diffs = diffSignatures(baseErrors, targetErrors, len(base.Dates()), len(target.Dates()))
+			m.diffCursor = 0
+			m.diffOffset = 0
+			m.diffReturnMode = m.mode
+			m.mode = ModeDiff
+			return nil
+		}, nil
+	})
+}
+
+// visibleDiffs returns the diff entries shown in the list (unchanged ones are
+// hidden unless showSame is set)
+func (m Model) visibleDiffs() []SignatureDiff {
+	if m.diffShowSame {
+		return m.diffs
+	}
+	var visible []SignatureDiff
+	for _, d := range m.diffs {
+		if d.Status != DiffSame {
+			visible = append(visible, d)
+		}
+	}
+	return visible
+}
+
+// drillIntoDiff opens the selected signature's errors in the three-panel view.
+// Target errors are shown when there are any, otherwise the baseline's.
+func (m *Model) drillIntoDiff() {
+	diffs := m.visibleDiffs()
+	if m.diffCursor >= len(diffs) {
+		return
+	}
+	d := diffs[m.diffCursor]
+
+	errors := d.TargetErrors
+	r := m.diffTarget
+	if len(errors) == 0 {
+		errors = d.BaseErrors
+		r = m.diffBase
+	}
+
+	if m.diffSaved == nil {
+		m.diffSaved = &diffReturn{allErrors: m.allErrors, selectedDate: m.selectedDate}
+	}
+	m.diffDrillLabel = fmt.Sprintf("%s %s", r, truncate(d.Signature, 40))
+	m.allErrors = errors
+	m.filteredErrors = errors
+	m.selectedDate = r.To
+	m.levelFilter = ""
+	m.componentFilter = ""
+	m.messageFilter = ""
+	m.buildGroups()
+	m.mode = ModeErrorList
+	m.focusedPanel = PanelGroups
+	m.groupCursor = 0
+	m.errorCursor = 0
+	m.groupOffset = 0
+	m.errorOffset = 0
+	m.updateContextPane()
+}
+
+// leaveDiffDrill returns from a drilled-in signature to the diff list
+func (m *Model) leaveDiffDrill() {
+	m.mode = ModeDiff
+	m.diffDrillLabel = ""
+}
+
+// closeDiff leaves the diff and restores the view it was opened from
+func (m *Model) closeDiff() {
+	if m.diffSaved != nil {
+		m.allErrors = m.diffSaved.allErrors
+		m.selectedDate = m.diffSaved.selectedDate
+		m.diffSaved = nil
+		m.filteredErrors = m.allErrors
+		m.levelFilter = ""
+		m.componentFilter = ""
+		m.messageFilter = ""
+		m.buildGroups()
+		m.groupCursor = 0
+		m.errorCursor = 0
+		m.groupOffset = 0
+		m.errorOffset = 0
+		m.updateContextPane()
+	}
+	m.mode = m.diffReturnMode
+	m.diffs = nil
+}
+
+func (m Model) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	diffs := m.visibleDiffs()
+	visibleCount := m.height - 9
+	if visibleCount < 5 {
+		visibleCount = 5
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Back):
+		m.closeDiff()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Up):
+		if m.diffCursor > 0 {
+			m.diffCursor--
+		}
+
+	case key.Matches(msg, m.keys.Down):
+		if m.diffCursor < len(diffs)-1 {
+			m.diffCursor++
+		}
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.diffCursor = max(m.diffCursor-visibleCount, 0)
+
+	case key.Matches(msg, m.keys.PageDown):
+		m.diffCursor = max(min(m.diffCursor+visibleCount, len(diffs)-1), 0)
+
+	case key.Matches(msg, m.keys.Home):
+		m.diffCursor = 0
+
+	case key.Matches(msg, m.keys.End):
+		m.diffCursor = max(len(diffs)-1, 0)
+
+	case key.Matches(msg, m.keys.Enter):
+		m.drillIntoDiff()
+		return m, nil
+
+	case key.Matches(msg, m.keys.ClearFilter):
+		m.diffShowSame = !m.diffShowSame
+		m.diffCursor = 0
+
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = !m.showHelp
+	}
+
+	if m.diffCursor < m.diffOffset {
+		m.diffOffset = m.diffCursor
+	}
+	if m.diffCursor >= m.diffOffset+visibleCount {
+		m.diffOffset = m.diffCursor - visibleCount + 1
+	}
+	return m, nil
+}
+
+func (m Model) viewDiff() string {
+	var sb strings.Builder
+
+	title := titleStyle.Render(fmt.Sprintf("Diff - %s - %s vs %s",
+		strings.ToUpper(m.selectedHutch), m.diffBase, m.diffTarget))
+	sb.WriteString(title)
+	sb.WriteString("\n\n")
+
+	counts := make(map[DiffStatus]int)
+	for _, d := range m.diffs {
+		counts[d.Status]++
+	}
+	sb.WriteString(fmt.Sprintf("%s new  %s gone  %s changed  %d unchanged  (rates are errors/day)\n\n",
+		criticalStyle.Render(fmt.Sprintf("%d", counts[DiffNew])),
+		cursorStyle.Render(fmt.Sprintf("%d", counts[DiffGone])),
+		errorStyle.Render(fmt.Sprintf("%d", counts[DiffChanged])),
+		counts[DiffSame]))
+
+	diffs := m.visibleDiffs()
+	if len(diffs) == 0 {
+		sb.WriteString(helpStyle.Render("No new, gone or changed signatures"))
+		sb.WriteString("\n")
+	}
+
+	visibleCount := m.height - 9
+	if visibleCount < 5 {
+		visibleCount = 5
+	}
+	start := m.diffOffset
+	end := min(start+visibleCount, len(diffs))
+
+	sigWidth := m.width - 32
+	if sigWidth < 20 {
+		sigWidth = 20
+	}
+
+	for i := start; i < end; i++ {
+		d := diffs[i]
+
+		cursor := "  "
+		if i == m.diffCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+
+		status := fmt.Sprintf("%-7s", d.Status)
+		switch d.Status {
+		case DiffNew:
+			status = criticalStyle.Render(status)
+		case DiffGone:
+			status = cursorStyle.Render(status)
+		case DiffChanged:
+			status = errorStyle.Render(status)
+		default:
+			status = normalStyle.Render(status)
+		}
+
+		rates := fmt.Sprintf("%6.1f → %-6.1f", d.BaseRate, d.TargetRate)
+		sig := truncate(d.Signature, sigWidth)
+		if i == m.diffCursor {
+			rates = selectedStyle.Render(rates)
+			sig = selectedStyle.Render(sig)
+		}
+
+		sb.WriteString(cursor)
+		sb.WriteString(status)
+		sb.WriteString(" ")
+		sb.WriteString(rates)
+		sb.WriteString(" ")
+		sb.WriteString(sig)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	if m.showHelp {
+		sb.WriteString(m.help.View(m.keys))
+	} else {
+		sb.WriteString(helpStyle.Render("↑↓ nav  enter open errors  a show unchanged  esc back  q quit"))
+	}
+	return sb.String()
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// Digest limits
+const (
+	shiftLength        = 8 * time.Hour // Shifts start at 00:00, 08:00 and 16:00 Pacific
+	maxDigestIncidents = 20            // Largest incidents listed
+)
+
+// runDigest implements the "digest" subcommand: a shift, day or week summary
+// across hutches, for pasting into the elog at shift change
+func runDigest(args []string) {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Path to daq_logs.db; several as a comma-separated list or glob")
+	hutches := fs.String("hutch", "", "Comma-separated hutches (default: all)")
+	window := fs.String("window", "shift", "Window length: shift (8h), day or week")
+	end := fs.String("end", "", "Window end, Pacific YYYY-MM-DD [HH:MM] (default: end of the last complete window)")
+	format := fs.String("format", "markdown", "Output format: markdown or text")
+	top := fs.Int("top", 10, "Number of components and signatures to list")
+	out := fs.String("out", "", "Output file (default stdout)")
+	fs.Parse(args)
+
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+		os.Exit(1)
+	}
+
+	if *format != "markdown" && *format != "text" {
+		fail("invalid format %q (want markdown or text)", *format)
+	}
+	start, stop, err := digestWindow(*window, *end, time.Now())
+	if err != nil {
+		fail("%v", err)
+	}
+
+	db := mustOpenDB(*dbPath, "daq-browser digest [--hutch tmo,mfx] [--window shift|day|week] [--end 'YYYY-MM-DD HH:MM'] [--format markdown|text] [--out FILE]")
+	defer db.Close()
+	store := mustNewStore(db)
+
+	var names []string
+	if *hutches != "" {
+		for _, h := range strings.Split(*hutches, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				names = append(names, h)
+			}
+		}
+	} else {
+		summaries, err := store.Hutches(context.Background())
+		if err != nil {
+			fail("loading hutches: %v", err)
+		}
+		for _, h := range summaries {
+			names = append(names, h.Hutch)
+		}
+	}
+
+	d, err := buildDigest(context.Background(), store, names, *window, start, stop, *top)
+	if err != nil {
+		fail("%v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fail("creating %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := d.write(w, *format == "markdown"); err != nil {
+		fail("writing digest: %v", err)
+	}
+}
+
+// digestWindow returns the [start, end) window to summarize. Without an explicit
+// end, the window is the last complete shift, day or week before now.
+func digestWindow(window, end string, now time.Time) (time.Time, time.Time, error) {
+	var stop time.Time
+	if end != "" {
+		var err error
+		stop, err = time.ParseInLocation("2006-01-02 15:04", end, daqlog.PacificLoc)
+		if err != nil {
+			stop, err = time.ParseInLocation("2006-01-02", end, daqlog.PacificLoc)
+		}
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end %q (want YYYY-MM-DD or 'YYYY-MM-DD HH:MM')", end)
+		}
+	} else {
+		// Last boundary at or before now, on the Pacific wall clock
+		p := now.In(daqlog.PacificLoc)
+		midnight := time.Date(p.Year(), p.Month(), p.Day(), 0, 0, 0, 0, daqlog.PacificLoc)
+		stop = midnight
+		if window == "shift" {
+			shiftHour := p.Hour() / int(shiftLength.Hours()) * int(shiftLength.Hours())
+			stop = time.Date(p.Year(), p.Month(), p.Day(), shiftHour, 0, 0, 0, daqlog.PacificLoc)
+		}
+	}
+
+	// Days are calendar days so DST changes give 23 or 25 hour windows
+	switch window {
+	case "shift":
+		return stop.Add(-shiftLength), stop, nil
+	case "day":
+		return stop.AddDate(0, 0, -1), stop, nil
+	case "week":
+		return stop.AddDate(0, 0, -7), stop, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid window %q (want shift, day or week)", window)
+}
+
+// previousStart returns the start of the window before one starting at start,
+// in the same calendar terms as digestWindow so that a DST change does not
+// shift it
+func previousStart(window string, start, end time.Time) time.Time {
+	switch window {
+	case "day":
+		return start.AddDate(0, 0, -1)
+	case "week":
+		return start.AddDate(0, 0, -7)
+	}
+	return start.Add(-end.Sub(start))
+}
+
+// digestCount is a named count in the current and previous windows
+type digestCount struct {
+	Name      string
+	Count     int
+	Criticals int
+	Previous  int
+}
+
+// digestSignature is a signature seen in the window but not the previous one
+type digestSignature struct {
+	Signature string
+	Count     int
+	Hutches   []string
+	First     time.Time
+}
+
+// digestIncident is an incident labeled with its hutch
+type digestIncident struct {
+	Hutch string
+	Size  int
+	*Incident
+}
+
+// digest is the summary of one window compared to the one before it
+type digest struct {
+	Window     string
+	Start, End time.Time
+	PrevStart  time.Time // Start of the previous window, which ends at Start
+	Total      digestCount
+	Hutches    []digestCount
+	Components []digestCount
+	Signatures []digestSignature
+	NewSigs    int // Total new signatures, including ones not listed
+	Incidents  []digestIncident
+	MoreInc    int // Incidents not listed
+	Untimed    int // Errors on the window's Pacific dates skipped for lack of a usable time
+}
+
+// buildDigest loads both windows for each hutch and summarizes them
+func buildDigest(ctx context.Context, store daqlog.Store, hutches []string, window string, start, end time.Time, top int) (*digest, error) {
+	prevStart := previousStart(window, start, end)
+	d := &digest{Window: window, Start: start, End: end, PrevStart: prevStart}
+
+	// Pacific dates covering both windows, and the current window's first one
+	dates := DateRange{
+		From: prevStart.In(daqlog.PacificLoc).Format("2006-01-02"),
+		To:   end.Add(-time.Nanosecond).In(daqlog.PacificLoc).Format("2006-01-02"),
+	}
+	firstDate := start.In(daqlog.PacificLoc).Format("2006-01-02")
+
+	var current, previous []daqlog.Error
+	for _, hutch := range hutches {
+		errors, err := LoadErrorsRange(ctx, store, hutch, dates)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", hutch, err)
+		}
+
+		var cur, prev []daqlog.Error
+		for _, e := range errors {
+			t, ok := daqlog.ErrorTime(e)
+			switch {
+			case !ok:
+				// Only the dates the window covers: the rest were loaded for
+				// the previous window
+				if e.DateRef >= firstDate && e.DateRef <= dates.To {
+					d.Untimed++
+				}
+			case !t.Before(start) && t.Before(end):
+				cur = append(cur, e)
+			case !t.Before(prevStart) && t.Before(start):
+				prev = append(prev, e)
+			}
+		}
+		current = append(current, cur...)
+		previous = append(previous, prev...)
+
+		d.Hutches = append(d.Hutches, digestCount{
+			Name: hutch, Count: len(cur), Criticals: countCriticals(cur), Previous: len(prev),
+		})
+
+		groups, _ := clusterIncidents(cur, defaultIncidentGap, daqlog.PacificLoc)
+		for _, g := range groups {
+			if len(g.Errors) >= minBurstSize {
+				d.Incidents = append(d.Incidents, digestIncident{hutch, len(g.Errors), g.Incident})
+			}
+		}
+	}
+
+	d.Total = digestCount{Count: len(current), Criticals: countCriticals(current), Previous: len(previous)}
+	d.Components = digestCounts(current, previous, top)
+	d.Signatures, d.NewSigs = newSignatures(current, previous, top)
+
+	// Keep the largest incidents, listed chronologically
+	if len(d.Incidents) > maxDigestIncidents {
+		sort.SliceStable(d.Incidents, func(i, j int) bool {
+			return d.Incidents[i].Size > d.Incidents[j].Size
+		})
+		d.MoreInc = len(d.Incidents) - maxDigestIncidents
+		d.Incidents = d.Incidents[:maxDigestIncidents]
+	}
+	sort.SliceStable(d.Incidents, func(i, j int) bool {
+		return d.Incidents[i].Start.Before(d.Incidents[j].Start)
+	})
+	return d, nil
+}
+
+// digestCounts returns the top components by error count in the current window
+func digestCounts(current, previous []daqlog.Error, top int) []digestCount {
+	byName := make(map[string]*digestCount)
+	get := func(name string) *digestCount {
+		c, ok := byName[name]
+		if !ok {
+			c = &digestCount{Name: name}
+			byName[name] = c
+		}
+		return c
+	}
+	for _, e := range current {
+		c := get(e.Component)
+		c.Count++
+		if e.LogLevel == "C" {
+			c.Criticals++
+		}
+	}
+	for _, e := range previous {
+		if c, ok := byName[e.Component]; ok {
+			c.Previous++
+		}
+	}
+
+	var counts []digestCount
+	for _, c := range byName {
+		counts = append(counts, *c)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
+
+// newSignatures returns the most frequent signatures absent from the previous
+// window, and how many new signatures there were in total
+func newSignatures(current, previous []daqlog.Error, top int) ([]digestSignature, int) {
+	var sigs []digestSignature
+	for _, d := range diffSignatures(previous, current, 1, 1) {
+		if d.Status != DiffNew {
+			continue
+		}
+		s := digestSignature{Signature: d.Signature, Count: len(d.TargetErrors)}
+		hutches := make(map[string]bool)
+		for _, e := range d.TargetErrors {
+			hutches[e.Hutch] = true
+			if t, ok := daqlog.ErrorTime(e); ok && (s.First.IsZero() || t.Before(s.First)) {
+				s.First = t
+			}
+		}
+		s.Hutches = sortedKeys(hutches)
+		sigs = append(sigs, s)
+	}
+	total := len(sigs)
+	if len(sigs) > top {
+		sigs = sigs[:top]
+	}
+	return sigs, total
+}
+
+// write renders the digest as Markdown or plain text
+func (d *digest) write(w io.Writer, markdown bool) error {
+	var sb strings.Builder
+	heading := func(title string) {
+		if markdown {
+			sb.WriteString("\n### " + title + "\n\n")
+		} else {
+			sb.WriteString("\n" + title + "\n" + strings.Repeat("-", utf8.RuneCountInString(title)) + "\n")
+		}
+	}
+	code := func(s string) string {
+		if markdown {
+			return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+		}
+		return s
+	}
+
+	title := fmt.Sprintf("DAQ error digest: %s %s", d.Window, formatDigestRange(d.Start, d.End))
+	if markdown {
+		sb.WriteString("## " + title + "\n\n")
+	} else {
+		sb.WriteString(title + "\n" + strings.Repeat("=", utf8.RuneCountInString(title)) + "\n\n")
+	}
+
+	fmt.Fprintf(&sb, "- Errors: %d (%s)\n", d.Total.Count, formatChange(d.Total.Count, d.Total.Previous))
+	fmt.Fprintf(&sb, "- Criticals: %d\n", d.Total.Criticals)
+	fmt.Fprintf(&sb, "- Previous window: %s\n", formatDigestRange(d.PrevStart, d.Start))
+	if d.Untimed > 0 {
+		fmt.Fprintf(&sb, "- %d errors from the window's Pacific dates had no usable time and were skipped\n", d.Untimed)
+	}
+
+	if len(d.Hutches) > 1 {
+		heading("Hutches")
+		for _, h := range d.Hutches {
+			fmt.Fprintf(&sb, "- %s: %d errors, %d critical (%s)\n", h.Name, h.Count, h.Criticals, formatChange(h.Count, h.Previous))
+		}
+	}
+
+	heading("Top components")
+	if len(d.Components) == 0 {
+		sb.WriteString("None\n")
+	}
+	for _, c := range d.Components {
+		fmt.Fprintf(&sb, "- %s: %d errors, %d critical (%s)\n", code(c.Name), c.Count, c.Criticals, formatChange(c.Count, c.Previous))
+	}
+
+	heading("New signatures")
+	if len(d.Signatures) == 0 {
+		sb.WriteString("None\n")
+	}
+	for _, s := range d.Signatures {
+		first := ""
+		if !s.First.IsZero() {
+			first = ", first " + daqlog.UTCToPacific(s.First).Format("01-02 15:04")
+		}
+		fmt.Fprintf(&sb, "- %s: %d in %s%s\n", code(truncate(s.Signature, 120)), s.Count, strings.Join(s.Hutches, ", "), first)
+	}
+	if more := d.NewSigs - len(d.Signatures); more > 0 {
+		fmt.Fprintf(&sb, "- ... and %d more\n", more)
+	}
+
+	heading("Incidents")
+	if len(d.Incidents) == 0 {
+		fmt.Fprintf(&sb, "None with %d or more errors\n", minBurstSize)
+	}
+	for _, inc := range d.Incidents {
+		start, end := daqlog.UTCToPacific(inc.Start), daqlog.UTCToPacific(inc.End)
+		fmt.Fprintf(&sb, "- %s %s–%s (%s) %s: %d errors, %s\n",
+			start.Format("01-02"), start.Format("15:04:05"), end.Format("15:04:05"),
+			formatDuration(inc.Duration()), inc.Hutch, inc.Size, inc.Summary())
+		fmt.Fprintf(&sb, "  components: %s\n", strings.Join(inc.Components, ", "))
+	}
+	if d.MoreInc > 0 {
+		fmt.Fprintf(&sb, "- ... and %d smaller incidents\n", d.MoreInc)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatDigestRange formats a window as Pacific times, e.g. "2025-11-19 08:00–16:00 PST"
+func formatDigestRange(start, end time.Time) string {
+	s, e := start.In(daqlog.PacificLoc), end.In(daqlog.PacificLoc)
+	if s.Format("2006-01-02") == e.Add(-time.Nanosecond).Format("2006-01-02") && e.Hour() != 0 {
+		return fmt.Sprintf("%s %s–%s %s", s.Format("2006-01-02"), s.Format("15:04"), e.Format("15:04"), e.Format("MST"))
+	}
+	return fmt.Sprintf("%s–%s %s", s.Format("2006-01-02 15:04"), e.Format("2006-01-02 15:04"), e.Format("MST"))
+}
+
+// formatChange describes a count relative to the previous window
+func formatChange(now, prev int) string {
+	if prev == 0 {
+		return "was 0"
+	}
+	pct := 100 * float64(now-prev) / float64(prev)
+	return fmt.Sprintf("%+.0f%% vs %d", pct, prev)
+}
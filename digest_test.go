@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+func TestDigestWindow(t *testing.T) {
+	pacific := func(s string) time.Time {
+		t.Helper()
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	for _, tt := range []struct {
+		window, end string
+		now         string // Used when end is empty
+		start, stop string // RFC3339 with the Pacific offset
+		prev        string
+	}{
+		// Shifts are eight hours of elapsed time, so across the November
+		// change the start reads 01:00 PDT and the previous shift one hour less
+		{"shift", "2025-11-02 08:00", "", "2025-11-02T01:00:00-07:00", "2025-11-02T08:00:00-08:00", "2025-11-01T17:00:00-07:00"},
+		{"shift", "2025-11-19 16:00", "", "2025-11-19T08:00:00-08:00", "2025-11-19T16:00:00-08:00", "2025-11-19T00:00:00-08:00"},
+		{"shift", "", "2026-03-08T10:30:00-07:00", "2026-03-07T23:00:00-08:00", "2026-03-08T08:00:00-07:00", "2026-03-07T15:00:00-08:00"},
+
+		// Days and weeks are calendar days: 25 hours on 2025-11-02, 23 on
+		// 2026-03-08, and the previous window keeps its own length
+		{"day", "2025-11-03", "", "2025-11-02T00:00:00-07:00", "2025-11-03T00:00:00-08:00", "2025-11-01T00:00:00-07:00"},
+		{"day", "2025-11-04", "", "2025-11-03T00:00:00-08:00", "2025-11-04T00:00:00-08:00", "2025-11-02T00:00:00-07:00"},
+		{"day", "2026-03-09", "", "2026-03-08T00:00:00-08:00", "2026-03-09T00:00:00-07:00", "2026-03-07T00:00:00-08:00"},
+		{"day", "", "2026-03-10T09:00:00-07:00", "2026-03-09T00:00:00-07:00", "2026-03-10T00:00:00-07:00", "2026-03-08T00:00:00-08:00"},
+		{"week", "2025-11-08", "", "2025-11-01T00:00:00-07:00", "2025-11-08T00:00:00-08:00", "2025-10-25T00:00:00-07:00"},
+		{"week", "2026-03-14", "", "2026-03-07T00:00:00-08:00", "2026-03-14T00:00:00-07:00", "2026-02-28T00:00:00-08:00"},
+		{"week", "", "2025-11-05T12:00:00-08:00", "2025-10-29T00:00:00-07:00", "2025-11-05T00:00:00-08:00", "2025-10-22T00:00:00-07:00"},
+	} {
+		var now time.Time
+		if tt.now != "" {
+			now = pacific(tt.now)
+		}
+		start, stop, err := digestWindow(tt.window, tt.end, now)
+		if err != nil {
+			t.Fatalf("%s ending %q: %v", tt.window, tt.end, err)
+		}
+		prev := previousStart(tt.window, start, stop)
+		format := func(t time.Time) string { return t.In(daqlog.PacificLoc).Format(time.RFC3339) }
+		if got := [3]string{format(start), format(stop), format(prev)}; got != [3]string{tt.start, tt.stop, tt.prev} {
+			t.Errorf("%s ending %q%s: [%s, %s) after %s, want [%s, %s) after %s", tt.window, tt.end, tt.now,
+				got[0], got[1], got[2], tt.start, tt.stop, tt.prev)
+		}
+	}
+
+	for _, tt := range []struct{ window, end string }{
+		{"month", "2025-11-03"},
+		{"day", "2025/11/03"},
+		{"shift", "2025-11-03 8am"},
+	} {
+		if _, _, err := digestWindow(tt.window, tt.end, time.Now()); err == nil {
+			t.Errorf("%s ending %q: no error", tt.window, tt.end)
+		}
+	}
+}
+
+func TestBuildDigestUntimed(t *testing.T) {
+	var errors []daqlog.Error
+	add := func(date, ts string) {
+		errors = append(errors, daqlog.Error{
+			ID: len(errors) + 1, Hutch: "tmo", DateRef: date, Timestamp: ts,
+			Component: "drp1", LogLevel: "E", ErrorType: "timeout", Message: "Timeout waiting for PV",
+			FilePath: "/logs/tmo/drp1.log", // No time in the name
+		})
+	}
+	// The 00:00-08:00 shift on 2025-11-19 is 08:00-16:00 UTC and the one
+	// before it 16:00-24:00 on the 18th
+	add("2025-11-19", "2025-11-19 09:00:00") // In the shift
+	add("2025-11-19", "2025-11-19 17:00:00") // In the shift after
+	add("2025-11-19", "")                    // Untimed on the shift's date
+	add("2025-11-18", "")                    // Untimed, only loaded for the previous shift
+
+	start, end, err := digestWindow("shift", "2025-11-19 08:00", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := buildDigest(context.Background(), daqlog.NewMemoryStore(errors), []string{"tmo"}, "shift", start, end, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Total.Count != 1 || d.Total.Previous != 0 || d.Untimed != 1 {
+		t.Errorf("count %d, previous %d, untimed %d; want 1, 0, 1", d.Total.Count, d.Total.Previous, d.Untimed)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// runExport implements the "export" subcommand: errors joined with their log
+// files for a date range, written as Parquet for pandas and Spark
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Path to daq_logs.db; several as a comma-separated list or glob")
+	format := fs.String("format", "parquet", "Output format (parquet)")
+	hutches := fs.String("hutch", "", "Comma-separated hutches (default all)")
+	from := fs.String("from", "", "First Pacific date (YYYY-MM-DD)")
+	to := fs.String("to", "", "Last Pacific date (YYYY-MM-DD, default --from)")
+	out := fs.String("out", "", "Output file, or directory with --partition")
+	withContext := fs.Bool("context", false, "Include the context_before and context_after columns")
+	partition := fs.Bool("partition", false, "Write one file per date under pacific_date=YYYY-MM-DD/ directories in --out")
+	dateColumn := fs.Bool("date-column", false, "Include a pacific_date column (without --partition)")
+	force := fs.Bool("force", false, "Overwrite existing output")
+	fs.Parse(args)
+
+	usage := "daq-browser export --from YYYY-MM-DD [--to YYYY-MM-DD] --out errors.parquet [--hutch tmo,rix] [--context] [--partition | --date-column] [--db path/to/daq_logs.db]"
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+		os.Exit(1)
+	}
+
+	if *format != "parquet" {
+		fail("unsupported format %q (supported: parquet)", *format)
+	}
+	if *from == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "Error: --from and --out are required")
+		fmt.Fprintln(os.Stderr, "Usage: "+usage)
+		os.Exit(1)
+	}
+	if *to == "" {
+		*to = *from
+	}
+	if *partition && *dateColumn {
+		// pyarrow and Spark reject a column that repeats a partition key
+		fail("--date-column duplicates the pacific_date partition key of --partition; use one or the other")
+	}
+	dates, err := pacificDates(*from, *to)
+	if err != nil {
+		fail("%v", err)
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fail("%s exists (use --force to overwrite)", *out)
+	}
+
+	db := mustOpenDB(*dbPath, usage)
+	defer db.Close()
+	store := mustNewStore(db)
+	ctx := context.Background()
+
+	list := splitList(*hutches)
+	if len(list) == 0 {
+		all, err := store.Hutches(ctx)
+		if err != nil {
+			fail("loading hutches: %v", err)
+		}
+		for _, h := range all {
+			list = append(list, h.Hutch)
+		}
+	}
+
+	// Without --partition every date goes to one file
+	var sink errorSink
+	if *partition {
+		// Only replace earlier partitions, never other files in --out
+		old, _ := filepath.Glob(filepath.Join(*out, "pacific_date=*"))
+		for _, dir := range old {
+			if err := os.RemoveAll(dir); err != nil {
+				fail("%v", err)
+			}
+		}
+	} else if sink, err = newParquetSink(*out, *withContext, *dateColumn); err != nil {
+		fail("%v", err)
+	}
+	closeSink := func() {
+		if sink == nil {
+			return
+		}
+		if err := sink.Close(); err != nil {
+			fail("writing %s: %v", sink.Name(), err)
+		}
+		sink = nil
+	}
+
+	total, files := 0, 0
+	for _, date := range dates {
+		var errors []daqlog.Error
+		for _, hutch := range list {
+			found, err := store.Errors(ctx, hutch, date)
+			if err != nil {
+				fail("loading %s %s: %v", hutch, date, err)
+			}
+			errors = append(errors, found...)
+		}
+		if *withContext {
+			if err := daqlog.FillContexts(ctx, store, errors); err != nil {
+				fail("loading context: %v", err)
+			}
+		}
+
+		// Spark and pyarrow skip partitions that have no files, so empty
+		// dates get none
+		if *partition && len(errors) == 0 {
+			continue
+		}
+		if *partition {
+			dir := filepath.Join(*out, "pacific_date="+date)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				fail("%v", err)
+			}
+			if sink, err = newParquetSink(filepath.Join(dir, "part-0.parquet"), *withContext, false); err != nil {
+				fail("%v", err)
+			}
+			files++
+		}
+		if err := sink.Write(errors); err != nil {
+			fail("writing %s: %v", sink.Name(), err)
+		}
+		total += len(errors)
+		if *partition {
+			closeSink()
+		}
+	}
+	closeSink()
+
+	span := dates[0]
+	if len(dates) > 1 {
+		span += " to " + dates[len(dates)-1]
+	}
+	if *partition {
+		fmt.Fprintf(os.Stderr, "Wrote %d errors (%s) in %d date partitions under %s\n", total, span, files, *out)
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %d errors (%s) to %s\n", total, span, *out)
+	}
+}
+
+// pacificDates lists the Pacific dates from first to last inclusive
+func pacificDates(first, last string) ([]string, error) {
+	start, err := time.Parse("2006-01-02", first)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", first)
+	}
+	end, err := time.Parse("2006-01-02", last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", last)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("--to %s is before --from %s", last, first)
+	}
+	var dates []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// parquetError is one exported error. Times are UTC timestamps and null when
+// unknown.
+type parquetError struct {
+	ID           int64      `parquet:"id"`
+	Hutch        string     `parquet:"hutch,dict"`
+	TimeUTC      *time.Time `parquet:"time_utc,optional,timestamp(microsecond:utc)"`
+	TimeSource   string     `parquet:"time_source,dict"` // db, filename, inferred or unknown
+	FileStartUTC *time.Time `parquet:"file_start_utc,optional,timestamp(microsecond:utc)"`
+	Component    string     `parquet:"component,dict"`
+	Host         string     `parquet:"host,dict"`
+	Level        string     `parquet:"level,dict"`
+	ErrorType    string     `parquet:"error_type,dict"`
+	Message      string     `parquet:"message"`
+	LineNumber   int32      `parquet:"line_number"`
+	FilePath     string     `parquet:"file_path,dict"`
+	Source       string     `parquet:"source,optional,dict"` // Database file, when several are read
+}
+
+// parquetErrorContext adds the context columns for --context
+type parquetErrorContext struct {
+	parquetError
+	ContextBefore string `parquet:"context_before,optional"`
+	ContextAfter  string `parquet:"context_after,optional"`
+}
+
+// parquetErrorDate adds the pacific_date column for --date-column
+type parquetErrorDate struct {
+	parquetError
+	PacificDate *int32 `parquet:"pacific_date,optional,date"`
+}
+
+// parquetErrorContextDate has both the context and pacific_date columns
+type parquetErrorContextDate struct {
+	parquetErrorContext
+	PacificDate *int32 `parquet:"pacific_date,optional,date"`
+}
+
+func toParquetError(e daqlog.Error) parquetError {
+	p := parquetError{
+		ID:         int64(e.ID),
+		Hutch:      e.Hutch,
+		TimeSource: e.TimeSource.String(),
+		Component:  e.Component,
+		Host:       e.Host,
+		Level:      e.LogLevel,
+		ErrorType:  e.ErrorType,
+		Message:    e.Message,
+		LineNumber: int32(e.LineNumber),
+		FilePath:   e.FilePath,
+		Source:     e.Source,
+	}
+	if t, ok := daqlog.ErrorTime(e); ok {
+		p.TimeUTC = &t
+	}
+	if !e.FileStartUTC.IsZero() {
+		start := e.FileStartUTC
+		p.FileStartUTC = &start
+	}
+	return p
+}
+
+func toParquetErrorContext(e daqlog.Error) parquetErrorContext {
+	return parquetErrorContext{
+		parquetError:  toParquetError(e),
+		ContextBefore: e.ContextBefore,
+		ContextAfter:  e.ContextAfter,
+	}
+}
+
+func toParquetErrorDate(e daqlog.Error) parquetErrorDate {
+	return parquetErrorDate{parquetError: toParquetError(e), PacificDate: pacificDate(e)}
+}
+
+func toParquetErrorContextDate(e daqlog.Error) parquetErrorContextDate {
+	return parquetErrorContextDate{parquetErrorContext: toParquetErrorContext(e), PacificDate: pacificDate(e)}
+}
+
+// pacificDate is the Pacific date the error was loaded under, which untimed
+// errors have too, as the days since 1970-01-01 of a Parquet DATE.
+// parquet-go writes a *time.Time DATE as truncated nanoseconds.
+func pacificDate(e daqlog.Error) *int32 {
+	d, err := time.Parse("2006-01-02", e.DateRef)
+	if err != nil {
+		return nil
+	}
+	days := int32(d.Unix() / 86400)
+	return &days
+}
+
+// errorSink is an open output file that errors are appended to
+type errorSink interface {
+	Name() string
+	Write(errors []daqlog.Error) error
+	Close() error
+}
+
+// parquetSink writes rows of type T converted from errors by row
+type parquetSink[T any] struct {
+	f   *os.File
+	w   *parquet.GenericWriter[T]
+	row func(daqlog.Error) T
+}
+
+// newParquetSink creates a Parquet file, with the context and pacific_date
+// columns if asked
+func newParquetSink(path string, withContext, withDate bool) (errorSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case withContext && withDate:
+		return newParquetSinkOf(f, toParquetErrorContextDate), nil
+	case withContext:
+		return newParquetSinkOf(f, toParquetErrorContext), nil
+	case withDate:
+		return newParquetSinkOf(f, toParquetErrorDate), nil
+	}
+	return newParquetSinkOf(f, toParquetError), nil
+}
+
+func newParquetSinkOf[T any](f *os.File, row func(daqlog.Error) T) *parquetSink[T] {
+	w := parquet.NewGenericWriter[T](f, parquet.Compression(&zstd.Codec{}))
+	return &parquetSink[T]{f: f, w: w, row: row}
+}
+
+func (s *parquetSink[T]) Name() string {
+	return s.f.Name()
+}
+
+func (s *parquetSink[T]) Write(errors []daqlog.Error) error {
+	rows := make([]T, len(errors))
+	for i, e := range errors {
+		rows[i] = s.row(e)
+	}
+	_, err := s.w.Write(rows)
+	return err
+}
+
+func (s *parquetSink[T]) Close() error {
+	if err := s.w.Close(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
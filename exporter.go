@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// errorTimeExpr is the SQL for an error's UTC time: its own full timestamp when it
+// has one, otherwise the start of its log file. T separators and trailing Zs are
+// normalized so the result compares correctly as a string.
+func errorTimeExpr(schema *daqlog.Schema) string {
+	return `RTRIM(REPLACE(CASE WHEN ` + schema.ErrorTimestamp + ` LIKE '____-__-__%'
+	THEN ` + schema.ErrorTimestamp + ` ELSE lf.start_timestamp_utc END, 'T', ' '), 'Z')`
+}
+
+// noiseFilter excludes the same slurm noise as the SQLite store
+const noiseFilter = `NOT (le.error_type = 'slurm' AND le.message LIKE '%CANCELLED%')
+	AND NOT (le.error_type = 'slurm' AND le.message LIKE '%Job step aborted%')`
+
+// runExporter implements the "exporter" subcommand: Prometheus metrics for the
+// error counts, refreshed from the database on an interval
+func runExporter(args []string) {
+	fs := flag.NewFlagSet("exporter", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Path to daq_logs.db")
+	listen := fs.String("listen", ":9120", "Address to listen on")
+	window := fs.Duration("window", time.Hour, "Sliding window for the windowed error gauges")
+	interval := fs.Duration("interval", 30*time.Second, "How often to re-query the database")
+	fs.Parse(args)
+
+	if *window <= 0 || *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --window and --interval must be positive")
+		os.Exit(1)
+	}
+
+	db := mustOpenLiveDB(*dbPath, "daq-browser exporter --db path/to/daq_logs.db [--listen :9120] [--window 1h] [--interval 30s]")
+	defer db.Close()
+	store := mustNewStore(db)
+	if len(store.Files()) > 1 {
+		fmt.Fprintln(os.Stderr, "Error: the exporter reads a single database; pass one --db")
+		os.Exit(1)
+	}
+
+	ex := &exporter{db: db, schema: store.Schema(), window: *window}
+	ex.refresh()
+	go func() {
+		for range time.Tick(*interval) {
+			ex.refresh()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", ex)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "DAQ error exporter: see /metrics")
+	})
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving DAQ error metrics on %s/metrics (window %s, refresh every %s)", *listen, *window, *interval)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running exporter: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// errorSeries is one label combination of the error metrics
+type errorSeries struct {
+	Hutch, Component, Level, ErrorType string
+}
+
+// metricsSnapshot is the result of one database refresh
+type metricsSnapshot struct {
+	windowCounts map[errorSeries]int
+	totalCounts  map[errorSeries]int
+	newest       map[string]time.Time // Newest error time per hutch
+	unparsed     int                  // Hutches whose newest error time could not be parsed
+	refreshedAt  time.Time
+	duration     time.Duration
+}
+
+// exporter caches the latest snapshot and renders it on each scrape
+type exporter struct {
+	db     *sql.DB
+	schema *daqlog.Schema
+	window time.Duration
+
+	mu       sync.RWMutex
+	snap     *metricsSnapshot
+	ok       bool // Whether the last refresh succeeded
+	failures int
+}
+
+// refresh re-queries the database. On failure the previous snapshot is kept
+// and the failure is reported through the exporter's own metrics.
+func (ex *exporter) refresh() {
+	snap, err := loadMetricsSnapshot(ex.db, ex.schema, ex.window, time.Now().UTC())
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	if err != nil {
+		log.Printf("Error refreshing metrics: %v", err)
+		ex.ok = false
+		ex.failures++
+		return
+	}
+	ex.snap = snap
+	ex.ok = true
+}
+
+// loadMetricsSnapshot counts errors by series over the window and overall
+func loadMetricsSnapshot(db *sql.DB, schema *daqlog.Schema, window time.Duration, now time.Time) (*metricsSnapshot, error) {
+	start := time.Now()
+	snap := &metricsSnapshot{newest: make(map[string]time.Time)}
+
+	cutoff := now.Add(-window).Format("2006-01-02 15:04:05")
+	var err error
+	snap.windowCounts, err = countErrorSeries(db, errorTimeExpr(schema)+" >= ?", cutoff)
+	if err != nil {
+		return nil, err
+	}
+	snap.totalCounts, err = countErrorSeries(db, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT lf.hutch, MAX(` + errorTimeExpr(schema) + `)
+		FROM log_errors le
+		JOIN log_files lf ON le.log_file_id = lf.id
+		WHERE ` + noiseFilter + `
+		GROUP BY lf.hutch`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hutch string
+		var newest sql.NullString
+		if err := rows.Scan(&hutch, &newest); err != nil {
+			return nil, err
+		}
+		if !newest.Valid {
+			continue
+		}
+		t, ok := daqlog.ParseUTCTimestamp(newest.String)
+		if !ok {
+			log.Printf("Cannot parse newest error time %q of hutch %s", newest.String, hutch)
+			snap.unparsed++
+			continue
+		}
+		snap.newest[hutch] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	snap.refreshedAt = now
+	snap.duration = time.Since(start)
+	return snap, nil
+}
+
+// countErrorSeries counts errors matching a WHERE condition by series
+func countErrorSeries(db *sql.DB, where string, args ...any) (map[errorSeries]int, error) {
+	rows, err := db.Query(`
+		SELECT lf.hutch, lf.component, le.log_level, le.error_type, COUNT(*)
+		FROM log_errors le
+		JOIN log_files lf ON le.log_file_id = lf.id
+		WHERE `+noiseFilter+` AND `+where+`
+		GROUP BY 1, 2, 3, 4`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[errorSeries]int)
+	for rows.Next() {
+		var s errorSeries
+		var n int
+		if err := rows.Scan(&s.Hutch, &s.Component, &s.Level, &s.ErrorType, &n); err != nil {
+			return nil, err
+		}
+		counts[s] = n
+	}
+	return counts, rows.Err()
+}
+
+// ServeHTTP renders the current snapshot in the Prometheus text format.
+// Ages are computed at scrape time so they keep growing between refreshes.
+func (ex *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ex.mu.RLock()
+	snap, ok, failures := ex.snap, ex.ok, ex.failures
+	ex.mu.RUnlock()
+
+	var b bytes.Buffer
+	now := time.Now().UTC()
+
+	if snap != nil {
+		writeMetricHeader(&b, "daq_errors_window", "gauge",
+			fmt.Sprintf("Errors in the last %s, by hutch, component, level and error type.", ex.window))
+		writeErrorSeries(&b, "daq_errors_window", snap.windowCounts)
+
+		writeMetricHeader(&b, "daq_errors_total", "counter",
+			"Errors ingested into the database, by hutch, component, level and error type.")
+		writeErrorSeries(&b, "daq_errors_total", snap.totalCounts)
+
+		hutches := make([]string, 0, len(snap.newest))
+		for h := range snap.newest {
+			hutches = append(hutches, h)
+		}
+		sort.Strings(hutches)
+
+		writeMetricHeader(&b, "daq_newest_error_timestamp_seconds", "gauge",
+			"Unix time of the newest ingested error, by hutch.")
+		for _, h := range hutches {
+			fmt.Fprintf(&b, "daq_newest_error_timestamp_seconds{hutch=\"%s\"} %d\n", escapeLabel(h), snap.newest[h].Unix())
+		}
+		writeMetricHeader(&b, "daq_newest_error_age_seconds", "gauge",
+			"Seconds since the newest ingested error, by hutch.")
+		for _, h := range hutches {
+			fmt.Fprintf(&b, "daq_newest_error_age_seconds{hutch=\"%s\"} %.0f\n", escapeLabel(h), now.Sub(snap.newest[h]).Seconds())
+		}
+
+		writeMetricHeader(&b, "daq_exporter_last_refresh_timestamp_seconds", "gauge",
+			"Unix time of the last successful database refresh.")
+		fmt.Fprintf(&b, "daq_exporter_last_refresh_timestamp_seconds %d\n", snap.refreshedAt.Unix())
+		writeMetricHeader(&b, "daq_exporter_refresh_duration_seconds", "gauge",
+			"Duration of the last successful database refresh.")
+		fmt.Fprintf(&b, "daq_exporter_refresh_duration_seconds %.3f\n", snap.duration.Seconds())
+		writeMetricHeader(&b, "daq_exporter_unparsed_timestamps", "gauge",
+			"Hutches left out of the newest error metrics because their newest error time could not be parsed.")
+		fmt.Fprintf(&b, "daq_exporter_unparsed_timestamps %d\n", snap.unparsed)
+	}
+
+	writeMetricHeader(&b, "daq_exporter_window_seconds", "gauge", "Length of the sliding window.")
+	fmt.Fprintf(&b, "daq_exporter_window_seconds %.0f\n", ex.window.Seconds())
+	writeMetricHeader(&b, "daq_exporter_up", "gauge", "Whether the last database refresh succeeded.")
+	fmt.Fprintf(&b, "daq_exporter_up %d\n", boolToInt(ok))
+	writeMetricHeader(&b, "daq_exporter_refresh_failures_total", "counter", "Failed database refreshes.")
+	fmt.Fprintf(&b, "daq_exporter_refresh_failures_total %d\n", failures)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric family
+func writeMetricHeader(b *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeErrorSeries writes one sample per series, sorted for stable output
+func writeErrorSeries(b *bytes.Buffer, name string, counts map[errorSeries]int) {
+	series := make([]errorSeries, 0, len(counts))
+	for s := range counts {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, c := series[i], series[j]
+		if a.Hutch != c.Hutch {
+			return a.Hutch < c.Hutch
+		}
+		if a.Component != c.Component {
+			return a.Component < c.Component
+		}
+		if a.Level != c.Level {
+			return a.Level < c.Level
+		}
+		return a.ErrorType < c.ErrorType
+	})
+	for _, s := range series {
+		fmt.Fprintf(b, "%s{hutch=\"%s\",component=\"%s\",level=\"%s\",error_type=\"%s\"} %d\n", name,
+			escapeLabel(s.Hutch), escapeLabel(s.Component), escapeLabel(s.Level), escapeLabel(s.ErrorType), counts[s])
+	}
+}
+
+// labelEscaper escapes label values for the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
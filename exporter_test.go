@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testExporterDB is an in-memory database mixing the timestamp layouts the
+// ingester writes: tmo uses T and Z, rix a space, and xpp has only a time of
+// day on a file whose start time has no seconds
+func testExporterDB(t *testing.T) (*sql.DB, *daqlog.Schema) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // Every connection would get its own empty database
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`
+		CREATE TABLE log_files (id INTEGER PRIMARY KEY, file_path TEXT, hutch TEXT, host TEXT,
+			component TEXT, start_timestamp_utc TEXT, error_count INTEGER);
+		CREATE TABLE log_errors (id INTEGER PRIMARY KEY, log_file_id INTEGER, line_number INTEGER,
+			timestamp_utc TEXT, log_level TEXT, error_type TEXT, message TEXT,
+			context_before TEXT, context_after TEXT);
+		INSERT INTO log_files VALUES
+			(1, '/logs/tmo/drp1.log', 'tmo', 'drp-srcf-cmp001', 'drp1', '2025-11-19T09:00:00Z', 3),
+			(2, '/logs/rix/xpm.log', 'rix', 'drp-neh-cmp001', 'xpm', '2025-11-19 08:00:00', 1),
+			(3, '/logs/xpp/teb0.log', 'xpp', 'drp-srcf-cmp002', 'teb0', '2025-11-19 10:00', 1);
+		INSERT INTO log_errors VALUES
+			(1, 1, 10, '2025-11-19T11:30:00Z', 'E', 'timeout', 'Timeout waiting for PV TMO:DAQ:1', '', ''),
+			(2, 1, 11, '2025-11-19T10:00:00Z', 'C', 'exception', 'DMA buffer 3 overflow', '', ''),
+			(3, 1, 12, '2025-11-19T11:45:00Z', 'E', 'slurm', 'slurmstepd: *** STEP 1.0 CANCELLED ***', '', ''),
+			(4, 2, 10, '2025-11-19 08:05:00', 'E', 'timeout', 'PGP lane 2 link down', '', ''),
+			(5, 3, 10, '10:00:01', 'E', 'timeout', 'Timeout waiting for transition', '', '');
+	`); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := daqlog.DetectSchema(t.Context(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, schema
+}
+
+func TestLoadMetricsSnapshot(t *testing.T) {
+	db, schema := testExporterDB(t)
+	now := time.Date(2025, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	snap, err := loadMetricsSnapshot(db, schema, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Error 3 is slurm noise; only error 1 falls in the last hour
+	tmo := errorSeries{"tmo", "drp1", "E", "timeout"}
+	if len(snap.windowCounts) != 1 || snap.windowCounts[tmo] != 1 {
+		t.Errorf("window counts = %v, want only %v", snap.windowCounts, tmo)
+	}
+	if len(snap.totalCounts) != 4 || snap.totalCounts[errorSeries{"tmo", "drp1", "C", "exception"}] != 1 {
+		t.Errorf("total counts = %v, want 4 series without the noise", snap.totalCounts)
+	}
+
+	want := map[string]time.Time{
+		"tmo": time.Date(2025, 11, 19, 11, 30, 0, 0, time.UTC),
+		"rix": time.Date(2025, 11, 19, 8, 5, 0, 0, time.UTC),
+	}
+	if len(snap.newest) != len(want) {
+		t.Errorf("newest = %v, want %v", snap.newest, want)
+	}
+	for hutch, w := range want {
+		if got := snap.newest[hutch]; !got.Equal(w) {
+			t.Errorf("newest %s = %v, want %v", hutch, got, w)
+		}
+	}
+	if snap.unparsed != 1 {
+		t.Errorf("unparsed = %d, want 1 (xpp)", snap.unparsed)
+	}
+}
+
+func TestExporterServeHTTP(t *testing.T) {
+	db, schema := testExporterDB(t)
+	ex := &exporter{db: db, schema: schema, window: time.Hour}
+
+	scrape := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ex.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		body, _ := io.ReadAll(rec.Body)
+		return string(body)
+	}
+
+	// Before the first refresh only the exporter's own metrics are there
+	body := scrape()
+	if !strings.Contains(body, "daq_exporter_up 0\n") || strings.Contains(body, "daq_errors_total{") {
+		t.Errorf("before refresh:\n%s", body)
+	}
+
+	ex.refresh()
+	body = scrape()
+	for _, line := range []string{
+		"daq_exporter_up 1\n",
+		`daq_errors_total{hutch="tmo",component="drp1",level="C",error_type="exception"} 1` + "\n",
+		`daq_errors_total{hutch="rix",component="xpm",level="E",error_type="timeout"} 1` + "\n",
+		`daq_newest_error_timestamp_seconds{hutch="tmo"} 1763551800` + "\n",
+		`daq_newest_error_timestamp_seconds{hutch="rix"} 1763539500` + "\n",
+		"daq_exporter_unparsed_timestamps 1\n",
+		"daq_exporter_window_seconds 3600\n",
+		"# TYPE daq_errors_total counter\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("scrape has no %q", line)
+		}
+	}
+	if strings.Contains(body, `daq_newest_error_age_seconds{hutch="xpp"}`) {
+		t.Errorf("xpp has a newest error age despite its unparsable time")
+	}
+	if strings.Index(body, `{hutch="rix"`) > strings.Index(body, `{hutch="tmo"`) {
+		t.Errorf("series are not sorted by hutch")
+	}
+}
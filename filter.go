@@ -2,8 +2,12 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
 )
 
 // applyFilters filters allErrors based on levelFilter and componentFilter
@@ -11,22 +15,9 @@ func (m *Model) applyFilters() {
 	m.filteredErrors = nil
 
 	for _, e := range m.allErrors {
-		// Level filter
-		if m.levelFilter != "" && e.LogLevel != m.levelFilter {
-			continue
-		}
-
-		// Component filter (case-insensitive substring match)
-		if m.componentFilter != "" {
-			if !strings.Contains(
-				strings.ToLower(e.Component),
-				strings.ToLower(m.componentFilter),
-			) {
-				continue
-			}
+		if matchesFilters(e, m.levelFilter, m.componentFilter, "") {
+			m.filteredErrors = append(m.filteredErrors, e)
 		}
-
-		m.filteredErrors = append(m.filteredErrors, e)
 	}
 
 	// Build groups from filtered errors
@@ -40,6 +31,27 @@ func (m *Model) applyFilters() {
 	m.updateContextPane()
 }
 
+// matchesFilters reports whether an error passes the level filter and the
+// case-insensitive component and message substring filters ("" matches all)
+func matchesFilters(e daqlog.Error, level, component, message string) bool {
+	// Level filter
+	if level != "" && e.LogLevel != level {
+		return false
+	}
+
+	// Component filter (case-insensitive substring match)
+	if component != "" && !strings.Contains(strings.ToLower(e.Component), strings.ToLower(component)) {
+		return false
+	}
+
+	// Message filter (case-insensitive substring match)
+	if message != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(message)) {
+		return false
+	}
+
+	return true
+}
+
 // clearFilters removes all filters but stays on the same error
 func (m *Model) clearFilters() {
 	// 1. Remember current error's ID before clearing
@@ -72,6 +84,14 @@ func (m *Model) findAndSelectError(errorID int) {
 			if e.ID == errorID {
 				m.groupCursor = gi
 				m.errorCursor = ei
+				// Index within the message-filtered list if the error is visible there
+				if m.messageFilter != "" {
+					if fi := indexOfError(m.getFilteredGroupErrors(), errorID); fi >= 0 {
+						m.errorCursor = fi
+					} else {
+						m.messageFilter = ""
+					}
+				}
 				// Adjust offsets to show cursor
 				pageSize := m.height - 10
 				if pageSize < 5 {
@@ -85,6 +105,36 @@ func (m *Model) findAndSelectError(errorID int) {
 	}
 }
 
+// regroupKeepSelection rebuilds groups and keeps the cursor on the same error
+func (m *Model) regroupKeepSelection() {
+	var currentErrorID int
+	if e := m.selectedError(); e != nil {
+		currentErrorID = e.ID
+	}
+
+	m.messageFilter = ""
+	m.buildGroups()
+	m.groupCursor = 0
+	m.errorCursor = 0
+	m.groupOffset = 0
+	m.errorOffset = 0
+	if currentErrorID > 0 {
+		m.findAndSelectError(currentErrorID)
+	}
+
+	m.updateContextPane()
+}
+
+// indexOfError returns the position of an error ID in a list, or -1
+func indexOfError(errors []daqlog.Error, errorID int) int {
+	for i, e := range errors {
+		if e.ID == errorID {
+			return i
+		}
+	}
+	return -1
+}
+
 // applyMessageFilter filters errors in the current group by message text
 func (m *Model) applyMessageFilter() {
 	// Reset cursor
@@ -94,7 +144,7 @@ func (m *Model) applyMessageFilter() {
 }
 
 // getFilteredGroupErrors returns errors for current group, filtered by messageFilter
-func (m Model) getFilteredGroupErrors() []Error {
+func (m Model) getFilteredGroupErrors() []daqlog.Error {
 	if m.groupCursor >= len(m.groups) {
 		return nil
 	}
@@ -107,10 +157,9 @@ func (m Model) getFilteredGroupErrors() []Error {
 	}
 
 	// Filter by message text
-	var filtered []Error
-	filterLower := strings.ToLower(m.messageFilter)
+	var filtered []daqlog.Error
 	for _, e := range group.Errors {
-		if strings.Contains(strings.ToLower(e.Message), filterLower) {
+		if matchesFilters(e, "", "", m.messageFilter) {
 			filtered = append(filtered, e)
 		}
 	}
@@ -118,49 +167,73 @@ func (m Model) getFilteredGroupErrors() []Error {
 }
 
 // buildGroups creates error groups from filteredErrors
-// Groups by (HH:MM, component) and sorts chronologically
+// Groups by (time bucket, grouping key) and sorts chronologically
 func (m *Model) buildGroups() {
 	m.groups = nil
 
-	if len(m.filteredErrors) == 0 {
+	// Bursts are detected over the full chronological list so that filtering
+	// never promotes a later error to "first in burst"
+	m.rootCauses = findRootCauses(m.allErrors)
+
+	if m.showIncidents {
+		m.buildIncidentGroups()
+		m.sortGroups()
 		return
 	}
 
-	// Group by (time, component)
+	m.groups = groupErrors(m.filteredErrors, m.groupKey, m.timeBucket, m.displayLoc)
+	m.sortGroups()
+}
+
+// groupErrors groups errors by (time bucket in loc, key) and sorts them
+// chronologically, then by key. Errors keep their input order within each group.
+func groupErrors(errors []daqlog.Error, groupKey GroupKey, bucket TimeBucket, loc *time.Location) []ErrorGroup {
+	if len(errors) == 0 {
+		return nil
+	}
+
+	// Group by (time, key)
 	groupMap := make(map[string]*ErrorGroup)
 	var groupOrder []string // Track insertion order for later sorting
 
-	for _, e := range m.filteredErrors {
-		timeStr := extractTimeHHMM(e.Timestamp, e.FilePath, e.DateRef)
-		if timeStr == "" {
-			timeStr = "??:??"
-		}
-		key := timeStr + "|" + e.Component
+	for _, e := range errors {
+		timeStr, start := bucket.Label(e, loc)
+		value := groupKey.Value(e)
+		key := timeStr + "|" + value
 
 		if g, ok := groupMap[key]; ok {
 			g.Errors = append(g.Errors, e)
 		} else {
 			groupMap[key] = &ErrorGroup{
-				Time:      timeStr,
-				Component: e.Component,
-				Errors:    []Error{e},
+				Time:   timeStr,
+				Start:  start,
+				Key:    value,
+				Errors: []daqlog.Error{e},
 			}
 			groupOrder = append(groupOrder, key)
 		}
 	}
 
 	// Convert map to slice
+	var groups []ErrorGroup
 	for _, key := range groupOrder {
-		m.groups = append(m.groups, *groupMap[key])
+		groups = append(groups, *groupMap[key])
 	}
 
-	// Sort groups chronologically by time, then by component
-	sort.Slice(m.groups, func(i, j int) bool {
-		if m.groups[i].Time != m.groups[j].Time {
-			return m.groups[i].Time < m.groups[j].Time
+	// Sort groups chronologically by bucket start (untimed last), then by key.
+	// Comparing instants rather than labels keeps a repeated DST hour and
+	// times past midnight in order.
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].Start, groups[j].Start
+		if !a.Equal(b) {
+			if a.IsZero() || b.IsZero() {
+				return b.IsZero()
+			}
+			return a.Before(b)
 		}
-		return m.groups[i].Component < m.groups[j].Component
+		return groups[i].Key < groups[j].Key
 	})
+	return groups
 }
 
 // jumpToTime finds the group closest to the given time and moves cursor there
@@ -179,21 +252,23 @@ func (m *Model) jumpToTime(timeStr string) {
 	bestDiff := 24 * 60 // Max possible diff
 
 	for i, g := range m.groups {
-		groupMinutes := parseTimeToMinutes(g.Time)
-		if groupMinutes < 0 {
-			continue
-		}
-		diff := abs(groupMinutes - targetMinutes)
-		if diff < bestDiff {
-			bestDiff = diff
-			bestIdx = i
+		for _, e := range g.Errors {
+			errMinutes := minutesOfDay(e, m.displayLoc)
+			if errMinutes < 0 {
+				continue
+			}
+			if diff := abs(errMinutes - targetMinutes); diff < bestDiff {
+				bestDiff = diff
+				bestIdx = i
+			}
 		}
 	}
 
+	m.markJump()
 	m.groupCursor = bestIdx
-	m.errorCursor = 0
+	m.errorCursor = FindNearestErrorIndex(m.getFilteredGroupErrors(), timeStr, m.displayLoc)
 	m.groupOffset = (m.groupCursor / m.pageSize) * m.pageSize
-	m.errorOffset = 0
+	m.errorOffset = (m.errorCursor / m.pageSize) * m.pageSize
 	m.updateContextPane()
 }
 
@@ -216,3 +291,63 @@ func (m *Model) totalErrorsInGroups() int {
 	}
 	return total
 }
+
+// FindNearestErrorIndex finds the error closest to targetTime (HH:MM wall-clock time in loc)
+func FindNearestErrorIndex(errors []daqlog.Error, targetTime string, loc *time.Location) int {
+	if len(errors) == 0 {
+		return 0
+	}
+
+	targetMinutes := parseTimeToMinutes(targetTime)
+	if targetMinutes < 0 {
+		return 0
+	}
+
+	bestIdx := 0
+	bestDiff := math.MaxInt32
+
+	for i, e := range errors {
+		errMinutes := minutesOfDay(e, loc)
+		if errMinutes < 0 {
+			continue
+		}
+
+		diff := abs(errMinutes - targetMinutes)
+		if diff < bestDiff {
+			bestDiff = diff
+			bestIdx = i
+		}
+	}
+	return bestIdx
+}
+
+// minutesOfDay returns an error's minutes since midnight in loc, or -1 when
+// its time is unknown
+func minutesOfDay(e daqlog.Error, loc *time.Location) int {
+	t, ok := daqlog.ErrorTime(e)
+	if !ok {
+		return -1
+	}
+	local := t.In(loc)
+	return local.Hour()*60 + local.Minute()
+}
+
+// parseTimeToMinutes converts HH:MM to minutes since midnight
+func parseTimeToMinutes(timeStr string) int {
+	if len(timeStr) < 5 || timeStr[2] != ':' {
+		return -1
+	}
+	var h, m int
+	_, err := fmt.Sscanf(timeStr, "%d:%d", &h, &m)
+	if err != nil {
+		return -1
+	}
+	return h*60 + m
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// fixtureSchema matches the tables written by the ingester
+const fixtureSchema = `
+CREATE TABLE log_files (
+	id INTEGER PRIMARY KEY,
+	filename TEXT,
+	file_path TEXT,
+	hutch TEXT,
+	log_date TEXT,
+	host TEXT,
+	component TEXT,
+	start_timestamp_utc TEXT,
+	error_count INTEGER
+);
+CREATE TABLE log_errors (
+	id INTEGER PRIMARY KEY,
+	log_file_id INTEGER,
+	line_number INTEGER,
+	timestamp_utc TEXT,
+	log_level TEXT,
+	error_type TEXT,
+	message TEXT,
+	context_before TEXT,
+	context_after TEXT
+);
+CREATE INDEX idx_log_files_hutch ON log_files(hutch, start_timestamp_utc);
+CREATE INDEX idx_log_errors_file ON log_errors(log_file_id);
+CREATE TABLE metadata (key TEXT PRIMARY KEY, value TEXT);
+INSERT INTO metadata VALUES ('schema_version', '2');
+`
+
+// fixtureConfig controls what gen-fixture writes
+type fixtureConfig struct {
+	hutches    []string
+	components []string
+	hosts      []string
+	start      time.Time // Pacific midnight of the first day
+	days       int
+	rate       float64 // Background errors per component per hour
+	bursts     int     // Bursts per hutch per day
+	noise      float64 // Fraction of extra errors that are slurm cancellation noise
+	untimed    float64 // Fraction of errors without timestamp_utc
+}
+
+// runGenFixture implements the "gen-fixture" subcommand: a synthetic database
+// for demos, bug reports and testing without access to the real one
+func runGenFixture(args []string) {
+	fs := flag.NewFlagSet("gen-fixture", flag.ExitOnError)
+	out := fs.String("out", "daq_logs.db", "Database file to write")
+	force := fs.Bool("force", false, "Overwrite an existing file")
+	hutches := fs.String("hutches", "tmo,rix,mfx", "Comma-separated hutches")
+	components := fs.String("components", "teb0,meb0,drp3,drp7,pvrtmon,control,groupca,timing", "Comma-separated components")
+	hosts := fs.Int("hosts", 6, "Number of DRP hosts per hutch")
+	days := fs.Int("days", 7, "Number of days")
+	start := fs.String("start", "", "First Pacific date (default: a range ending today that includes the latest DST change when --dst)")
+	dst := fs.Bool("dst", true, "Include the most recent DST change in the default date range")
+	rate := fs.Float64("rate", 0.5, "Background errors per component per hour")
+	bursts := fs.Int("bursts", 3, "Bursts per hutch per day")
+	noise := fs.Float64("noise", 0.15, "Slurm cancellation noise, as a fraction of other errors")
+	untimed := fs.Float64("untimed", 0.05, "Fraction of errors without a timestamp (filename time only)")
+	seed := fs.Int64("seed", 1, "Random seed")
+	live := fs.Bool("live", false, "After writing, keep appending errors in real time until interrupted")
+	liveRate := fs.Float64("live-rate", 6, "Live background errors per hutch per minute")
+	fs.Parse(args)
+
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+		os.Exit(1)
+	}
+
+	cfg := fixtureConfig{
+		hutches:    splitList(*hutches),
+		components: splitList(*components),
+		days:       *days,
+		rate:       *rate,
+		bursts:     *bursts,
+		noise:      *noise,
+		untimed:    *untimed,
+	}
+	if len(cfg.hutches) == 0 || len(cfg.components) == 0 || *hosts < 1 || cfg.days < 1 {
+		fail("need at least one hutch, component, host and day")
+	}
+	for i := 1; i <= *hosts; i++ {
+		cfg.hosts = append(cfg.hosts, fmt.Sprintf("drp-srcf-cmp%03d", i))
+	}
+
+	today := time.Now().In(daqlog.PacificLoc)
+	last := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, daqlog.PacificLoc)
+	cfg.start = last.AddDate(0, 0, 1-cfg.days)
+	switch {
+	case *start != "":
+		t, err := time.ParseInLocation("2006-01-02", *start, daqlog.PacificLoc)
+		if err != nil {
+			fail("invalid start %q (want YYYY-MM-DD)", *start)
+		}
+		cfg.start = t
+	case *dst:
+		// Center the range on the most recent DST change
+		if change, ok := lastDSTChange(last); ok {
+			cfg.start = change.AddDate(0, 0, -cfg.days/2)
+		}
+	}
+
+	if _, err := os.Stat(*out); err == nil {
+		if !*force {
+			fail("%s exists (use --force to overwrite)", *out)
+		}
+		for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+			os.Remove(*out + suffix)
+		}
+	}
+
+	// WAL lets the browser and exporter read while --live writes
+	uri := &url.URL{Scheme: "file", Path: *out, RawQuery: "_journal_mode=WAL", OmitHost: true}
+	db, err := sql.Open("sqlite3", uri.String())
+	if err != nil {
+		fail("opening %s: %v", *out, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(fixtureSchema); err != nil {
+		fail("creating schema: %v", err)
+	}
+
+	gen := &fixtureGen{cfg: cfg, rng: rand.New(rand.NewSource(*seed))}
+	files, errs, err := gen.writeHistory(db)
+	if err != nil {
+		fail("writing fixture: %v", err)
+	}
+	end := cfg.start.AddDate(0, 0, cfg.days-1)
+	fmt.Fprintf(os.Stderr, "Wrote %s: %d files, %d errors, %s to %s (%s)\n", *out, files, errs,
+		cfg.start.Format("2006-01-02"), end.Format("2006-01-02"), strings.Join(cfg.hutches, ", "))
+
+	if *live {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		fmt.Fprintf(os.Stderr, "Appending live errors to %s (Ctrl-C to stop)\n", *out)
+		if err := gen.runLive(ctx, db, *liveRate); err != nil {
+			fail("live append: %v", err)
+		}
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// lastDSTChange returns the Pacific midnight of the most recent day, on or
+// before day, whose UTC offset differs from the day before
+func lastDSTChange(day time.Time) (time.Time, bool) {
+	for i := 0; i < 366; i++ {
+		d := day.AddDate(0, 0, -i)
+		_, off := d.AddDate(0, 0, 1).Zone()
+		_, prevOff := d.Zone()
+		if off != prevOff {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// fixtureGen generates files and errors from a seeded random source
+type fixtureGen struct {
+	cfg    fixtureConfig
+	rng    *rand.Rand
+	nextID int // Next log_files id
+	lines  map[int]int
+}
+
+// fixtureFile is a log file being generated
+type fixtureFile struct {
+	id        int
+	hutch     string
+	host      string
+	component string
+	start     time.Time
+	end       time.Time
+	errors    []fixtureError
+}
+
+// fixtureError is a generated error row
+type fixtureError struct {
+	t       time.Time
+	level   string
+	typ     string
+	message string
+	untimed bool
+}
+
+// writeHistory writes every day for every hutch in one transaction
+func (g *fixtureGen) writeHistory(db *sql.DB) (int, int, error) {
+	var files []*fixtureFile
+	for _, hutch := range g.cfg.hutches {
+		// Sessions run across day boundaries, so generate the whole range at once
+		from := g.cfg.start
+		to := g.cfg.start.AddDate(0, 0, g.cfg.days)
+		hutchFiles := g.sessions(hutch, from, to)
+
+		for day := 0; day < g.cfg.days; day++ {
+			dayStart := from.AddDate(0, 0, day)
+			dayEnd := dayStart.AddDate(0, 0, 1)
+			for i := 0; i < g.cfg.bursts; i++ {
+				g.burst(hutchFiles, g.randomTime(dayStart, dayEnd))
+			}
+		}
+		files = append(files, hutchFiles...)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Only files with errors are ingested
+	written, total := 0, 0
+	for _, f := range files {
+		if len(f.errors) == 0 {
+			continue
+		}
+		if err := g.insertFile(tx, f); err != nil {
+			return 0, 0, err
+		}
+		if err := g.insertErrors(tx, f, f.errors); err != nil {
+			return 0, 0, err
+		}
+		written++
+		total += len(f.errors)
+	}
+	return written, total, tx.Commit()
+}
+
+// sessions tiles [from, to) with DAQ process sessions for each component. A
+// session is one log file of 1 to 8 hours, so some start late in the evening
+// and run past midnight.
+func (g *fixtureGen) sessions(hutch string, from, to time.Time) []*fixtureFile {
+	var files []*fixtureFile
+	for ci, comp := range g.cfg.components {
+		host := g.componentHost(hutch, ci)
+		t := from.Add(time.Duration(g.rng.Intn(3600)) * time.Second)
+		for t.Before(to) {
+			length := time.Hour + time.Duration(g.rng.Int63n(int64(7*time.Hour)))
+			f := &fixtureFile{hutch: hutch, host: host, component: comp, start: t, end: t.Add(length)}
+			g.background(f)
+			files = append(files, f)
+			// Short gap before the process is restarted
+			t = f.end.Add(time.Duration(1+g.rng.Intn(20)) * time.Minute)
+		}
+	}
+	return files
+}
+
+// componentHost returns the host a component runs on. Control-level processes
+// run on the hutch's DAQ machine, the rest are spread over the DRP nodes.
+func (g *fixtureGen) componentHost(hutch string, ci int) string {
+	comp := g.cfg.components[ci]
+	if strings.HasPrefix(comp, "pvr") || comp == "control" || comp == "groupca" {
+		return hutch + "-daq"
+	}
+	return g.cfg.hosts[ci%len(g.cfg.hosts)]
+}
+
+// background adds scattered errors to a file at the configured rate
+func (g *fixtureGen) background(f *fixtureFile) {
+	n := g.poisson(g.cfg.rate * f.end.Sub(f.start).Hours())
+	for i := 0; i < n; i++ {
+		f.errors = append(f.errors, g.randomError(g.randomTime(f.start, f.end), f))
+	}
+	noise := g.poisson(g.cfg.noise * float64(n))
+	for i := 0; i < noise; i++ {
+		f.errors = append(f.errors, g.noiseError(g.randomTime(f.start, f.end), f))
+	}
+}
+
+// burst adds a cascade starting at t: one component fails first (usually with a
+// critical) and others follow within a few minutes
+func (g *fixtureGen) burst(files []*fixtureFile, t time.Time) {
+	var active []*fixtureFile
+	for _, f := range files {
+		if !t.Before(f.start) && t.Before(f.end) {
+			active = append(active, f)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+	g.rng.Shuffle(len(active), func(i, j int) { active[i], active[j] = active[j], active[i] })
+	involved := active[:1+g.rng.Intn(min(len(active), 4))]
+
+	first := involved[0]
+	e := g.randomError(t, first)
+	if g.rng.Float64() < 0.7 {
+		e.level = "C"
+	}
+	first.errors = append(first.errors, e)
+
+	span := time.Duration(30+g.rng.Intn(150)) * time.Second
+	for i := 0; i < 8+g.rng.Intn(30); i++ {
+		f := involved[g.rng.Intn(len(involved))]
+		at := t.Add(time.Second + time.Duration(g.rng.Int63n(int64(span))))
+		if at.Before(f.end) {
+			f.errors = append(f.errors, g.randomError(at, f))
+		}
+	}
+}
+
+// fixtureMessages are message templates; %s is filled by fillMessage
+var fixtureMessages = []struct {
+	typ     string
+	message string
+}{
+	{"timeout", "Timeout waiting for PV %pv from %ip"},
+	{"timeout", "Timed out after %n ms waiting for transition Configure"},
+	{"timeout", "Event builder timeout: %n contributions missing"},
+	{"exception", `Exception in thread 0x%hex: "bad state"`},
+	{"exception", "Traceback (most recent call last): KeyError: '%key'"},
+	{"exception", "std::runtime_error: DMA buffer %n overflow"},
+	{"connection", "Connection refused to %host"},
+	{"connection", "ZMQ socket tcp://%ip:%port closed unexpectedly"},
+	{"system", "Out of memory: killed process %n"},
+	{"system", "Disk quota exceeded writing /cds/data/drpsrcf/%hutch/xtc/run%n.xtc2"},
+	{"system", "PGP lane %n link down"},
+	{"slurm", "srun: error: %host: task %n: Exited with exit code 1"},
+}
+
+// randomError returns a background error for a file
+func (g *fixtureGen) randomError(t time.Time, f *fixtureFile) fixtureError {
+	tmpl := fixtureMessages[g.rng.Intn(len(fixtureMessages))]
+	level := "E"
+	if g.rng.Float64() < 0.15 {
+		level = "C"
+	}
+	return fixtureError{
+		t:       t,
+		level:   level,
+		typ:     tmpl.typ,
+		message: g.fillMessage(tmpl.message, f),
+		untimed: g.rng.Float64() < g.cfg.untimed,
+	}
+}
+
+// noiseError returns slurm cancellation noise, which the browser hides
+func (g *fixtureGen) noiseError(t time.Time, f *fixtureFile) fixtureError {
+	message := fmt.Sprintf("slurmstepd: error: *** STEP %d.0 ON %s CANCELLED AT %s ***",
+		100000+g.rng.Intn(900000), f.host, t.UTC().Format("2006-01-02T15:04:05"))
+	if g.rng.Intn(2) == 0 {
+		message = "srun: Job step aborted: Waiting up to 32 seconds for job step to finish."
+	}
+	return fixtureError{t: t, level: "E", typ: "slurm", message: message}
+}
+
+// fillMessage replaces the placeholders in a message template
+func (g *fixtureGen) fillMessage(tmpl string, f *fixtureFile) string {
+	r := strings.NewReplacer(
+		"%pv", fmt.Sprintf("%s:DAQ:%d", strings.ToUpper(f.hutch), 1+g.rng.Intn(8)),
+		"%ip", fmt.Sprintf("172.21.%d.%d", g.rng.Intn(4), 1+g.rng.Intn(250)),
+		"%port", fmt.Sprintf("%d", 30000+g.rng.Intn(1000)),
+		"%host", g.cfg.hosts[g.rng.Intn(len(g.cfg.hosts))],
+		"%hex", fmt.Sprintf("7f%06x", g.rng.Intn(1<<24)),
+		"%key", []string{"readout_group", "xpm", "detName"}[g.rng.Intn(3)],
+		"%hutch", f.hutch,
+		"%n", fmt.Sprintf("%d", 1+g.rng.Intn(5000)),
+	)
+	return r.Replace(tmpl)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertFile writes a log_files row. The filename carries the Pacific start time.
+func (g *fixtureGen) insertFile(tx execer, f *fixtureFile) error {
+	g.nextID++
+	f.id = g.nextID
+	start := f.start.In(daqlog.PacificLoc)
+	filename := fmt.Sprintf("%s_%s:%s.log", start.Format("02_15:04:05"), f.host, f.component)
+	path := fmt.Sprintf("/cds/data/drpsrcf/%s/scratch/logs/%s/%s", f.hutch, start.Format("2006/01"), filename)
+	_, err := tx.Exec(`INSERT INTO log_files
+		(id, filename, file_path, hutch, log_date, host, component, start_timestamp_utc, error_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.id, filename, path, f.hutch, start.Format("2006-01-02"), f.host, f.component,
+		f.start.UTC().Format("2006-01-02 15:04:05"), len(f.errors))
+	return err
+}
+
+// insertErrors writes error rows for a file in time order, with increasing line
+// numbers and surrounding context lines
+func (g *fixtureGen) insertErrors(tx execer, f *fixtureFile, errs []fixtureError) error {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].t.Before(errs[j].t) })
+	if g.lines == nil {
+		g.lines = make(map[int]int)
+	}
+	for _, e := range errs {
+		// Lines advance with time, so later errors sit further into the file
+		line := max(g.lines[f.id]+11, int(e.t.Sub(f.start).Seconds()/2)+12)
+		g.lines[f.id] = line
+
+		var ts any
+		if !e.untimed {
+			ts = e.t.UTC().Format("2006-01-02 15:04:05")
+		}
+		before, after := g.context(e, f)
+		if _, err := tx.Exec(`INSERT INTO log_errors
+			(log_file_id, line_number, timestamp_utc, log_level, error_type, message, context_before, context_after)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			f.id, line, ts, e.level, e.typ, e.message, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// context returns ten plausible log lines before and after an error
+func (g *fixtureGen) context(e fixtureError, f *fixtureFile) (string, string) {
+	steps := []string{
+		"Received transition %s",
+		"%s: readout group 0 enabled",
+		"Memory usage %d MB",
+		"Batch %d complete",
+		"PV %s connected",
+	}
+	line := func(t time.Time) string {
+		ts := t.In(daqlog.PacificLoc).Format("2006-01-02 15:04:05.000")
+		switch s := steps[g.rng.Intn(len(steps))]; {
+		case strings.Contains(s, "%d"):
+			return fmt.Sprintf("[%s] %s", ts, fmt.Sprintf(s, g.rng.Intn(10000)))
+		case strings.HasPrefix(s, "PV"):
+			return fmt.Sprintf("[%s] %s", ts, fmt.Sprintf(s, strings.ToUpper(f.hutch)+":DAQ:1"))
+		default:
+			return fmt.Sprintf("[%s] %s", ts, fmt.Sprintf(s, []string{"Configure", "Enable", "BeginRun"}[g.rng.Intn(3)]))
+		}
+	}
+
+	var before, after []string
+	for i := 10; i > 0; i-- {
+		before = append(before, line(e.t.Add(-time.Duration(i)*200*time.Millisecond)))
+	}
+	for i := 1; i <= 10; i++ {
+		after = append(after, line(e.t.Add(time.Duration(i)*200*time.Millisecond)))
+	}
+	return strings.Join(before, "\n"), strings.Join(after, "\n")
+}
+
+// runLive appends errors as they "happen": background errors at rate per hutch
+// per minute, and an occasional burst. Each hutch gets fresh log files that
+// start now; their error_count is kept current.
+func (g *fixtureGen) runLive(ctx context.Context, db *sql.DB, rate float64) error {
+	start := time.Now().Truncate(time.Second)
+	var files []*fixtureFile
+	byHutch := make(map[string][]*fixtureFile)
+	for _, hutch := range g.cfg.hutches {
+		for ci, comp := range g.cfg.components {
+			f := &fixtureFile{
+				hutch:     hutch,
+				host:      g.componentHost(hutch, ci),
+				component: comp,
+				start:     start,
+				end:       start.Add(24 * time.Hour),
+			}
+			if err := g.insertFile(db, f); err != nil {
+				return err
+			}
+			files = append(files, f)
+			byHutch[hutch] = append(byHutch[hutch], f)
+		}
+	}
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	total := 0
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stderr, "\nAppended %d errors\n", total)
+			return nil
+		case now := <-tick.C:
+			now = now.Truncate(time.Second)
+			for _, hutch := range g.cfg.hutches {
+				hutchFiles := byHutch[hutch]
+				n := g.poisson(rate / 60)
+				for i := 0; i < n; i++ {
+					f := hutchFiles[g.rng.Intn(len(hutchFiles))]
+					f.errors = append(f.errors, g.randomError(now, f))
+				}
+				// About one burst per hutch every ten minutes
+				if g.rng.Float64() < 1.0/600 {
+					g.burst(hutchFiles, now)
+				}
+			}
+
+			for _, f := range files {
+				// Burst errors are scheduled up to a few minutes ahead; write
+				// what has happened and keep the rest pending
+				var due, pending []fixtureError
+				for _, e := range f.errors {
+					if e.t.After(now) {
+						pending = append(pending, e)
+					} else {
+						due = append(due, e)
+					}
+				}
+				f.errors = pending
+				if len(due) == 0 {
+					continue
+				}
+				if err := g.insertErrors(db, f, due); err != nil {
+					return err
+				}
+				if _, err := db.Exec(`UPDATE log_files SET error_count = error_count + ? WHERE id = ?`, len(due), f.id); err != nil {
+					return err
+				}
+				total += len(due)
+			}
+			fmt.Fprintf(os.Stderr, "\r%s  %d errors appended", now.In(daqlog.PacificLoc).Format("15:04:05"), total)
+		}
+	}
+}
+
+// randomTime returns a uniformly random time in [from, to)
+func (g *fixtureGen) randomTime(from, to time.Time) time.Time {
+	return from.Add(time.Duration(g.rng.Int63n(int64(to.Sub(from))))).Truncate(time.Second)
+}
+
+// poisson draws from a Poisson distribution with the given mean
+func (g *fixtureGen) poisson(mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	// Knuth's method is fine for the small means used here; large means use a
+	// normal approximation
+	if mean > 50 {
+		return max(0, int(mean+g.rng.NormFloat64()*math.Sqrt(mean)+0.5))
+	}
+	l, k, p := math.Exp(-mean), 0, 1.0
+	for {
+		p *= g.rng.Float64()
+		if p < l {
+			return k
+		}
+		k++
+	}
+}
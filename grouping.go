@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// GroupKey selects the error field used to group errors in the groups panel
+type GroupKey int
+
+const (
+	GroupByComponent GroupKey = iota
+	GroupByHost
+	GroupByErrorType
+	GroupByLogFile
+	GroupBySignature
+	GroupByLevel
+	numGroupKeys
+)
+
+// String returns the short name shown in the groups pane header
+func (k GroupKey) String() string {
+	switch k {
+	case GroupByHost:
+		return "host"
+	case GroupByErrorType:
+		return "type"
+	case GroupByLogFile:
+		return "file"
+	case GroupBySignature:
+		return "signature"
+	case GroupByLevel:
+		return "level"
+	default:
+		return "component"
+	}
+}
+
+// Value returns the grouping value of an error for this key
+func (k GroupKey) Value(e daqlog.Error) string {
+	switch k {
+	case GroupByHost:
+		return e.Host
+	case GroupByErrorType:
+		return e.ErrorType
+	case GroupByLogFile:
+		return filepath.Base(e.FilePath)
+	case GroupBySignature:
+		return messageSignature(e.Message)
+	case GroupByLevel:
+		return e.LogLevel
+	default:
+		return e.Component
+	}
+}
+
+// TimeBucket selects how finely groups are split by time
+type TimeBucket int
+
+const (
+	BucketNone TimeBucket = iota
+	Bucket10s
+	Bucket1m
+	Bucket5m
+	Bucket15m
+	Bucket1h
+	numTimeBuckets
+)
+
+// Duration returns the bucket width (0 for no time bucketing)
+func (b TimeBucket) Duration() time.Duration {
+	switch b {
+	case Bucket10s:
+		return 10 * time.Second
+	case Bucket1m:
+		return time.Minute
+	case Bucket5m:
+		return 5 * time.Minute
+	case Bucket15m:
+		return 15 * time.Minute
+	case Bucket1h:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+// String returns the short name shown in the groups pane header
+func (b TimeBucket) String() string {
+	switch b {
+	case Bucket10s:
+		return "10s"
+	case Bucket1m:
+		return "1m"
+	case Bucket5m:
+		return "5m"
+	case Bucket15m:
+		return "15m"
+	case Bucket1h:
+		return "1h"
+	default:
+		return "all day"
+	}
+}
+
+// Start returns the start of the bucket containing t. Truncation happens on
+// the wall clock in loc, so buckets line up with local boundaries even in
+// zones with half-hour offsets.
+func (b TimeBucket) Start(t time.Time, loc *time.Location) time.Time {
+	_, offset := t.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(b.Duration()).Add(-shift)
+}
+
+// Label returns the time label in loc of the bucket an error falls in, and the
+// bucket's start (zero when the error has no time or b is BucketNone). Labels
+// in a repeated DST hour carry their UTC offset.
+func (b TimeBucket) Label(e daqlog.Error, loc *time.Location) (string, time.Time) {
+	if b == BucketNone {
+		return "", time.Time{}
+	}
+
+	t, ok := daqlog.ErrorTime(e)
+	if !ok {
+		if b == Bucket10s {
+			return "??:??:??", time.Time{}
+		}
+		return "??:??", time.Time{}
+	}
+	start := b.Start(t, loc)
+	if b == Bucket10s {
+		return daqlog.FormatClock(start, loc, "15:04:05"), start
+	}
+	return daqlog.FormatClock(start, loc, "15:04"), start
+}
+
+// cycleGroupKey advances to the next grouping key, keeping the selection
+func (m *Model) cycleGroupKey() {
+	m.groupKey = (m.groupKey + 1) % numGroupKeys
+	m.regroupKeepSelection()
+}
+
+// cycleTimeBucket advances to the next time bucket, keeping the selection
+func (m *Model) cycleTimeBucket() {
+	m.timeBucket = (m.timeBucket + 1) % numTimeBuckets
+	m.regroupKeepSelection()
+}
+
+// groupLabel returns the "time key" label for a group, omitting an empty time
+func groupLabel(g ErrorGroup) string {
+	if g.Time == "" {
+		return g.Key
+	}
+	return g.Time + " " + g.Key
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logTokens are the kinds of token highlighted in log lines, tried in order
+// at each position so that e.g. a timestamp wins over the numbers inside it
+var logTokens = []struct {
+	pattern string
+	style   func(tok string) lipgloss.Style
+}{
+	{`"[^"]*"|'[^']*'`, fixed(quotedTokenStyle)},
+	{`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?Z?\b`, fixed(timeTokenStyle)},
+	{`\b\d{2}:\d{2}:\d{2}(?:\.\d+)?\b`, fixed(timeTokenStyle)},
+	{`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`, fixed(hostTokenStyle)},
+	{`\b0x[0-9a-fA-F]+\b`, fixed(numberTokenStyle)},
+	{`\[[CEWID]\]|<[CEWID]>|\b(?:CRITICAL|FATAL|ERROR|WARN(?:ING)?|INFO|DEBUG)\b|\berror:`, levelTokenStyle},
+	{`\b[A-Z][A-Z0-9_]*(?::[A-Z0-9_]+)+\b`, fixed(pvTokenStyle)},
+	{`\b[a-z][a-z0-9]*(?:-[a-z0-9]+)*-[a-z]*\d+[a-z0-9]*\b|\b[a-z][a-z0-9-]*(?:\.[a-z][a-z0-9-]*)+\.(?:edu|gov|org|com|net)\b`, fixed(hostTokenStyle)},
+	{`\b\d+(?:\.\d+)?\b`, fixed(numberTokenStyle)},
+}
+
+// logTokenRe matches any log token, with one capture group per kind
+var logTokenRe = func() *regexp.Regexp {
+	parts := make([]string, len(logTokens))
+	for i, t := range logTokens {
+		parts[i] = "(" + t.pattern + ")"
+	}
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}()
+
+// fixed styles every token of a kind the same way
+func fixed(s lipgloss.Style) func(string) lipgloss.Style {
+	return func(string) lipgloss.Style { return s }
+}
+
+// levelTokenStyle colors a log-level tag by its severity
+func levelTokenStyle(tok string) lipgloss.Style {
+	switch strings.Trim(strings.ToUpper(tok), "[]<>:") {
+	case "C", "CRITICAL", "FATAL", "E", "ERROR":
+		return criticalStyle
+	case "W", "WARN", "WARNING":
+		return errorStyle
+	}
+	return normalStyle
+}
+
+// highlightLog colors the timestamps, level tags, hosts and IPs, hex
+// addresses, PV names, numbers and quoted strings in plain log text
+func highlightLog(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range logTokenRe.FindAllStringSubmatchIndex(text, -1) {
+		for k := range logTokens {
+			start, end := loc[2+2*k], loc[3+2*k]
+			if start < 0 {
+				continue
+			}
+			tok := text[start:end]
+			sb.WriteString(text[last:start])
+			sb.WriteString(logTokens[k].style(tok).Render(tok))
+			last = end
+			break
+		}
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// markFilter highlights the case-insensitive occurrences of filter in text,
+// which starts at cell col of the rendered line. selected keeps the selection
+// background behind them.
+func markFilter(line string, col int, text, filter string, selected bool) string {
+	style := filterMatchStyle
+	if selected {
+		style = style.Background(colorBlue)
+	}
+	var ranges []lipgloss.Range
+	for _, hit := range findMatches(text, filter) {
+		ranges = append(ranges, lipgloss.NewRange(col+hit.start, col+hit.end, style))
+	}
+	return lipgloss.StyleRanges(line, ranges...)
+}
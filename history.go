@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxHistory caps the navigation history; the oldest entries drop off first
+const maxHistory = 100
+
+// viewState is a place in the browser: hutch, date, filters, grouping and the
+// selected error. It is what the history records and restores.
+type viewState struct {
+	Mode  Mode   `json:"mode"`
+	Hutch string `json:"hutch"` // Selected hutch, or the hutch under the cursor in the hutch picker
+	Date  string `json:"date"`  // Selected date, or the date under the cursor in the date picker
+
+	// Grouping, kept across dates
+	GroupKey      GroupKey      `json:"group_key"`
+	TimeBucket    TimeBucket    `json:"time_bucket"`
+	GroupSort     GroupSort     `json:"group_sort"`
+	ErrorSort     ErrorSort     `json:"error_sort"`
+	ShowIncidents bool          `json:"show_incidents,omitempty"`
+	IncidentGap   time.Duration `json:"incident_gap,omitempty"`
+
+	// Error list only
+	LevelFilter     string `json:"level_filter,omitempty"`
+	ComponentFilter string `json:"component_filter,omitempty"`
+	MessageFilter   string `json:"message_filter,omitempty"`
+	FocusedPanel    Panel  `json:"focused_panel"`
+	GroupCursor     int    `json:"group_cursor"`
+	ErrorID         int    `json:"error_id,omitempty"` // Selected error, 0 if none
+	Error           string `json:"error,omitempty"`    // "time component message" of the selected error, for the history list
+}
+
+// location is the part of a view state that makes it a separate history entry.
+// Moving the cursor updates the current entry; changing anything else starts a
+// new one, so filter changes can be undone with back.
+func (v viewState) location() viewState {
+	switch v.Mode {
+	case ModeHutchPicker:
+		return viewState{Mode: v.Mode}
+	case ModeDatePicker:
+		return viewState{Mode: v.Mode, Hutch: v.Hutch}
+	}
+	v.FocusedPanel = 0
+	v.GroupCursor = 0
+	v.ErrorID = 0
+	v.Error = ""
+	return v
+}
+
+// Label describes the view state for the history list
+func (v viewState) Label() string {
+	switch v.Mode {
+	case ModeHutchPicker:
+		return "Hutches"
+	case ModeDatePicker:
+		return strings.ToUpper(v.Hutch) + " dates"
+	}
+
+	parts := []string{strings.ToUpper(v.Hutch) + " " + v.Date}
+	if v.LevelFilter == "C" {
+		parts = append(parts, "critical")
+	}
+	if v.ComponentFilter != "" {
+		parts = append(parts, "component~"+v.ComponentFilter)
+	}
+	if v.MessageFilter != "" {
+		parts = append(parts, "message~"+v.MessageFilter)
+	}
+	if v.ShowIncidents {
+		parts = append(parts, "incidents")
+	} else if v.GroupKey != GroupByComponent || v.TimeBucket != Bucket1m {
+		parts = append(parts, fmt.Sprintf("by %s/%s", v.GroupKey, v.TimeBucket))
+	}
+	if v.Error != "" {
+		parts = append(parts, v.Error)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// navHistory is a browser-style history of view states. It is shared by
+// pointer between copies of the model; each side of a split has its own.
+type navHistory struct {
+	entries []viewState
+	index   int  // Current entry
+	jump    bool // The next change starts a new entry even at the same location
+}
+
+// clone copies the history for the second side of a split
+func (h *navHistory) clone() *navHistory {
+	c := *h
+	c.entries = append([]viewState(nil), h.entries...)
+	return &c
+}
+
+// markJump records the selection change about to happen as its own entry
+// (jumping to a time or root cause), so back returns to where the jump started
+func (m *Model) markJump() {
+	if m.history != nil {
+		m.history.jump = true
+	}
+}
+
+// captureView returns the current view state
+func (m Model) captureView() viewState {
+	v := viewState{
+		Mode:          m.mode,
+		GroupKey:      m.groupKey,
+		TimeBucket:    m.timeBucket,
+		GroupSort:     m.groupSort,
+		ErrorSort:     m.errorSort,
+		ShowIncidents: m.showIncidents,
+		IncidentGap:   m.incidentGap,
+	}
+	switch m.mode {
+	case ModeHutchPicker:
+		if m.hutchCursor < len(m.hutches) {
+			v.Hutch = m.hutches[m.hutchCursor].Hutch
+		}
+	case ModeDatePicker:
+		v.Hutch = m.selectedHutch
+		if m.cursor < len(m.dates) {
+			v.Date = m.dates[m.cursor].Date
+		}
+	case ModeErrorList:
+		v.Hutch = m.selectedHutch
+		v.Date = m.selectedDate
+		v.LevelFilter = m.levelFilter
+		v.ComponentFilter = m.componentFilter
+		v.MessageFilter = m.messageFilter
+		v.FocusedPanel = m.focusedPanel
+		v.GroupCursor = m.groupCursor
+		if e := m.selectedError(); e != nil {
+			v.ErrorID = e.ID
+			clock := "--:--:--"
+			if t, ok := daqlog.ErrorTime(*e); ok {
+				clock = daqlog.FormatClock(t, m.displayLoc, "15:04:05")
+			}
+			v.Error = fmt.Sprintf("%s %s %s", clock, e.Component, truncate(e.Message, 40))
+		}
+	}
+	return v
+}
+
+// syncHistory records the current view after each update: a new entry when
+// the location changed, otherwise the current entry follows the cursor.
+// Views the history cannot restore (correlation, diff, a drilled-in diff
+// signature) and half-finished loads are not recorded.
+func (m *Model) syncHistory() {
+	h := m.history
+	if h == nil || m.loading != nil || m.diffDrillLabel != "" {
+		return
+	}
+	switch m.mode {
+	case ModeHutchPicker, ModeDatePicker, ModeErrorList:
+	default:
+		return
+	}
+
+	v := m.captureView()
+	jump := h.jump
+	h.jump = false
+	if len(h.entries) > 0 && !jump && h.entries[h.index].location() == v.location() {
+		h.entries[h.index] = v
+		return
+	}
+	if len(h.entries) > 0 && h.entries[h.index] == v {
+		return
+	}
+
+	// A new entry drops everything forward of the current one
+	if len(h.entries) > 0 {
+		h.entries = h.entries[:h.index+1]
+	}
+	h.entries = append(h.entries, v)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.index = len(h.entries) - 1
+}
+
+// historyGo restores history entry i. Views that need loading become current
+// once the load finishes, so a cancelled load leaves the history where it was.
+func (m *Model) historyGo(i int) tea.Cmd {
+	h := m.history
+	if h == nil || i < 0 || i >= len(h.entries) || i == h.index {
+		return nil
+	}
+	return m.openView(h.entries[i], func(m *Model) {
+		m.history.index = i
+	})
+}
+
+// openView goes to a view state from wherever the browser is, loading the
+// hutch list, dates and errors it needs. done runs once the view is shown.
+func (m *Model) openView(v viewState, done func(*Model)) tea.Cmd {
+	// Before the hutch list has loaded, load it on the way
+	load := m.loadDates
+	if m.hutches == nil {
+		load = m.loadHutches
+	}
+
+	switch v.Mode {
+	case ModeHutchPicker:
+		if m.hutches == nil {
+			return m.loadHutches("", "", func(m *Model) {
+				m.selectHutch(v.Hutch)
+				done(m)
+			})
+		}
+		m.backToHutchPicker()
+		m.selectHutch(v.Hutch)
+		done(m)
+		return nil
+
+	case ModeDatePicker:
+		selectDate := func(m *Model) {
+			for j, d := range m.dates {
+				if d.Date == v.Date {
+					m.cursor = j
+					break
+				}
+			}
+			done(m)
+		}
+		if v.Hutch == m.selectedHutch && m.dates != nil {
+			m.backToDatePicker()
+			selectDate(m)
+			return nil
+		}
+		return load(v.Hutch, "", selectDate)
+
+	case ModeErrorList:
+		restore := func(m *Model) {
+			m.applyView(v)
+			done(m)
+		}
+		switch {
+		case m.mode == ModeErrorList && m.diffDrillLabel == "" && v.Hutch == m.selectedHutch && v.Date == m.selectedDate:
+			restore(m)
+			return nil
+		case v.Hutch == m.selectedHutch && m.dates != nil:
+			return m.loadErrors(v.Date, restore)
+		default:
+			return load(v.Hutch, v.Date, restore)
+		}
+	}
+	return nil
+}
+
+// selectHutch moves the hutch picker's cursor to a hutch
+func (m *Model) selectHutch(hutch string) {
+	for i, h := range m.hutches {
+		if h.Hutch == hutch {
+			m.hutchCursor = i
+			return
+		}
+	}
+}
+
+// applyGrouping sets the grouping of a view state; the groups are rebuilt by
+// the caller
+func (m *Model) applyGrouping(v viewState) {
+	m.groupKey = v.GroupKey
+	m.timeBucket = v.TimeBucket
+	m.groupSort = v.GroupSort
+	m.errorSort = v.ErrorSort
+	m.showIncidents = v.ShowIncidents
+	m.incidentGap = v.IncidentGap
+}
+
+// applyView restores an error list's filters, grouping and selection. An
+// error that no longer exists leaves the cursor on the same group row.
+func (m *Model) applyView(v viewState) {
+	m.levelFilter = v.LevelFilter
+	m.componentFilter = v.ComponentFilter
+	m.applyGrouping(v)
+	m.messageFilter = ""
+	m.applyFilters()
+
+	m.messageFilter = v.MessageFilter
+	m.focusedPanel = v.FocusedPanel
+	if v.ErrorID > 0 && m.groupsContain(v.ErrorID) {
+		m.findAndSelectError(v.ErrorID)
+	} else if len(m.groups) > 0 {
+		m.groupCursor = min(v.GroupCursor, len(m.groups)-1)
+		pageSize := max(m.height-10, 5)
+		m.groupOffset = (m.groupCursor / pageSize) * pageSize
+	}
+	m.updateContextPane()
+}
+
+// updateHistoryKey handles back, forward and opening the history list from
+// the hutch picker, date picker and error list. handled is false for other keys.
+func (m Model) updateHistoryKey(msg tea.KeyMsg) (model Model, cmd tea.Cmd, handled bool) {
+	if m.history == nil || m.diffDrillLabel != "" {
+		return m, nil, false
+	}
+	switch {
+	case key.Matches(msg, m.keys.HistoryBack):
+		return m, m.historyGo(m.history.index - 1), true
+	case key.Matches(msg, m.keys.HistoryForward):
+		return m, m.historyGo(m.history.index + 1), true
+	case key.Matches(msg, m.keys.History):
+		m.showHistory = true
+		m.historyCursor = m.history.index
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// updateHistoryList handles keys while the history list is open
+func (m Model) updateHistoryList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	last := len(m.history.entries) - 1
+	switch {
+	case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.History), msg.String() == "q":
+		m.showHistory = false
+
+	// Newest entries are listed first, so up moves forward in time
+	case key.Matches(msg, m.keys.Up):
+		if m.historyCursor < last {
+			m.historyCursor++
+		}
+
+	case key.Matches(msg, m.keys.Down):
+		if m.historyCursor > 0 {
+			m.historyCursor--
+		}
+
+	case key.Matches(msg, m.keys.Home):
+		m.historyCursor = last
+
+	case key.Matches(msg, m.keys.End):
+		m.historyCursor = 0
+
+	case key.Matches(msg, m.keys.Enter):
+		m.showHistory = false
+		return m, m.historyGo(m.historyCursor)
+	}
+	return m, nil
+}
+
+// viewHistory renders the history list, newest first
+func (m Model) viewHistory() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("DAQ Error Browser - History"))
+	sb.WriteString("\n\n")
+
+	h := m.history
+	visible := max(m.height-6, 1)
+	top := len(h.entries) - 1
+	if top-m.historyCursor >= visible {
+		top = m.historyCursor + visible - 1
+	}
+
+	for i := top; i >= 0 && top-i < visible; i-- {
+		cursor := "  "
+		style := normalStyle
+		if i == m.historyCursor {
+			cursor = cursorStyle.Render("> ")
+			style = selectedStyle
+		}
+		marker := "  "
+		if i == h.index {
+			marker = "● "
+		}
+		line := truncate(h.entries[i].Label(), max(m.width-8, 20))
+		sb.WriteString(cursor)
+		sb.WriteString(marker)
+		sb.WriteString(style.Render(line))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("↑↓ select  enter go  esc close  (● current)"))
+	return sb.String()
+}
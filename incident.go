@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// Quiet-gap bounds for incident clustering
+const (
+	defaultIncidentGap = 60 * time.Second
+	minIncidentGap     = 5 * time.Second
+	maxIncidentGap     = time.Hour
+)
+
+// Incident is a cluster of errors with no quiet gap longer than the threshold
+type Incident struct {
+	Name       string // Start time and first component, e.g. "19:31:04 drp3"
+	Start      time.Time
+	End        time.Time
+	Components []string
+	Hosts      []string
+	PeakRate   int // Most errors seen in any 60-second window
+	Criticals  int
+}
+
+// Duration returns how long the incident lasted
+func (inc Incident) Duration() time.Duration {
+	return inc.End.Sub(inc.Start)
+}
+
+// Summary returns a one-line description of the incident's spread and intensity
+func (inc Incident) Summary() string {
+	return fmt.Sprintf("%d comps · %d hosts · peak %d/min · %d crit",
+		len(inc.Components), len(inc.Hosts), inc.PeakRate, inc.Criticals)
+}
+
+// buildIncidentGroups clusters filteredErrors into incidents, one group each.
+// Errors without a usable time are collected in a trailing "??:??" group.
+func (m *Model) buildIncidentGroups() {
+	groups, untimed := clusterIncidents(m.filteredErrors, m.currentIncidentGap(), m.displayLoc)
+	m.groups = append(m.groups, groups...)
+	if len(untimed) > 0 {
+		m.groups = append(m.groups, ErrorGroup{Time: "??:??", Key: "(no time)", Errors: untimed})
+	}
+}
+
+// clusterIncidents splits errors into incidents separated by quiet gaps longer
+// than gap, one chronological group each, labelled in loc. Errors without a
+// usable time are returned separately.
+func clusterIncidents(errors []daqlog.Error, gap time.Duration, loc *time.Location) ([]ErrorGroup, []daqlog.Error) {
+	type timed struct {
+		e daqlog.Error
+		t time.Time
+	}
+	var errs []timed
+	var untimed []daqlog.Error
+	for _, e := range errors {
+		if t, ok := daqlog.ErrorTime(e); ok {
+			errs = append(errs, timed{e, t})
+		} else {
+			untimed = append(untimed, e)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].t.Before(errs[j].t)
+	})
+
+	var groups []ErrorGroup
+	var cluster []timed
+	flush := func() {
+		if len(cluster) == 0 {
+			return
+		}
+		group := ErrorGroup{}
+		var times []time.Time
+		components := make(map[string]bool)
+		hosts := make(map[string]bool)
+		inc := &Incident{Start: cluster[0].t, End: cluster[len(cluster)-1].t}
+		for _, c := range cluster {
+			group.Errors = append(group.Errors, c.e)
+			times = append(times, c.t)
+			components[c.e.Component] = true
+			hosts[c.e.Host] = true
+			if c.e.LogLevel == "C" {
+				inc.Criticals++
+			}
+		}
+		inc.Components = sortedKeys(components)
+		inc.Hosts = sortedKeys(hosts)
+		inc.PeakRate = peakRate(times, time.Minute)
+
+		first := cluster[0].e
+		inc.Name = daqlog.FormatClock(inc.Start, loc, "15:04:05") + " " + first.Component
+		group.Time = daqlog.FormatClock(inc.Start, loc, "15:04")
+		group.Start = inc.Start
+		group.Key = first.Component
+		group.Incident = inc
+		groups = append(groups, group)
+		cluster = nil
+	}
+
+	for _, c := range errs {
+		if len(cluster) > 0 && c.t.Sub(cluster[len(cluster)-1].t) > gap {
+			flush()
+		}
+		cluster = append(cluster, c)
+	}
+	flush()
+
+	return groups, untimed
+}
+
+// peakRate returns the largest number of sorted times falling in any window
+func peakRate(times []time.Time, window time.Duration) int {
+	peak := 0
+	lo := 0
+	for hi := range times {
+		for times[hi].Sub(times[lo]) >= window {
+			lo++
+		}
+		if n := hi - lo + 1; n > peak {
+			peak = n
+		}
+	}
+	return peak
+}
+
+// sortedKeys returns the keys of a string set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// currentIncidentGap returns the quiet gap in effect
+func (m Model) currentIncidentGap() time.Duration {
+	if m.incidentGap == 0 {
+		return defaultIncidentGap
+	}
+	return m.incidentGap
+}
+
+// setIncidentGap changes the quiet gap and re-clusters, keeping the selection
+func (m *Model) setIncidentGap(gap time.Duration) {
+	if gap < minIncidentGap {
+		gap = minIncidentGap
+	}
+	if gap > maxIncidentGap {
+		gap = maxIncidentGap
+	}
+	m.incidentGap = gap
+	m.regroupKeepSelection()
+}
+
+// formatIncidentLine renders an incident row for the groups pane
+func formatIncidentLine(g ErrorGroup, compWidth int) string {
+	inc := g.Incident
+	comp := g.Key
+	if extra := len(inc.Components) - 1; extra > 0 {
+		comp = fmt.Sprintf("%s+%d", comp, extra)
+	}
+	if len(comp) > compWidth {
+		comp = comp[:compWidth-3] + "..."
+	}
+	return fmt.Sprintf("%s %6s %-*s (%d)", g.Time, formatDuration(inc.Duration()), compWidth, comp, len(g.Errors))
+}
+
+// formatDuration renders a compact duration such as "45s", "3m12s" or "1h05m"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// incidentDetails renders the full incident description for the zoomed view
+func incidentDetails(inc *Incident, loc *time.Location) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Incident %s: %s – %s (%s)\n",
+		inc.Name, daqlog.FormatClock(inc.Start, loc, "15:04:05"), daqlog.FormatClock(inc.End, loc, "15:04:05"),
+		formatDuration(inc.Duration())))
+	sb.WriteString(fmt.Sprintf("  %s\n", inc.Summary()))
+	sb.WriteString(fmt.Sprintf("  Components: %s\n", strings.Join(inc.Components, ", ")))
+	sb.WriteString(fmt.Sprintf("  Hosts: %s\n", strings.Join(inc.Hosts, ", ")))
+	return sb.String()
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadState describes the database request in flight. Only one runs at a time:
+// starting another cancels it.
+type loadState struct {
+	id      int64
+	label   string
+	mode    Mode // Mode the load was started from
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+// loadedMsg carries a finished load back to Update. apply runs only if the
+// load is still the current one, so results the user has moved away from are
+// dropped.
+type loadedMsg struct {
+	id    int64
+	err   error
+	apply func(m *Model) tea.Cmd
+}
+
+// loadFunc queries the store and returns how to apply the result to the model
+type loadFunc func(ctx context.Context) (func(m *Model) tea.Cmd, error)
+
+// lastLoadID numbers loads across both sides of a split so IDs never collide
+var lastLoadID atomic.Int64
+
+// startLoad cancels any load in flight and runs fn in the background
+func (m *Model) startLoad(label string, fn loadFunc) tea.Cmd {
+	m.cancelLoad()
+	m.loadNote = ""
+
+	ctx, cancel := context.WithCancel(context.Background())
+	id := lastLoadID.Add(1)
+	m.loading = &loadState{
+		id:      id,
+		label:   label,
+		mode:    m.mode,
+		started: time.Now(),
+		cancel:  cancel,
+	}
+
+	load := func() tea.Msg {
+		defer cancel()
+		apply, err := fn(ctx)
+		return loadedMsg{id: id, err: err, apply: apply}
+	}
+	return tea.Batch(load, m.spinner.Tick)
+}
+
+// cancelLoad abandons the load in flight, if any
+func (m *Model) cancelLoad() {
+	if m.loading == nil {
+		return
+	}
+	m.loading.cancel()
+	m.loading = nil
+}
+
+// handleLoaded applies a finished load if it is still current
+func (m *Model) handleLoaded(msg loadedMsg) tea.Cmd {
+	if m.loading == nil || msg.id != m.loading.id {
+		return nil // Cancelled or superseded
+	}
+	m.loading = nil
+	if msg.err != nil {
+		m.err = msg.err
+		return nil
+	}
+	return msg.apply(m)
+}
+
+// dropStaleLoad cancels the load in flight once the user has navigated to
+// another view, since its result would land somewhere they no longer are
+func (m *Model) dropStaleLoad() {
+	if m.loading != nil && m.mode != m.loading.mode {
+		m.cancelLoad()
+	}
+}
+
+// viewLoading renders the spinner line, or the note left by a cancelled load
+func (m Model) viewLoading() string {
+	if m.loading != nil {
+		elapsed := time.Since(m.loading.started).Seconds()
+		return fmt.Sprintf("%s %s… %.1fs  %s", m.spinner.View(), m.loading.label, elapsed, helpStyle.Render("esc cancel"))
+	}
+	if m.loadNote != "" {
+		return helpStyle.Render(m.loadNote)
+	}
+	return ""
+}
+
+// newSpinner returns the spinner shown while loading
+func newSpinner() spinner.Model {
+	return spinner.New(spinner.WithSpinner(spinner.MiniDot), spinner.WithStyle(cursorStyle))
+}
+
+// loadStart opens the startup view. An error ID is looked up first to find
+// its hutch and date; if it no longer exists the browser opens as if it had
+// not been given.
+func (m *Model) loadStart(start viewState, initialTime string) tea.Cmd {
+	var note string // Set when the error is missing; shown once loading ends
+	then := func(m *Model) {
+		if m.mode == ModeErrorList {
+			m.applyStart(start)
+		}
+		if initialTime != "" {
+			m.jumpToTime(initialTime)
+			m.updateContextPane()
+		}
+		if note != "" {
+			m.loadNote = note
+		}
+	}
+	if start.ErrorID <= 0 {
+		return m.loadHutches(start.Hutch, start.Date, then)
+	}
+
+	store, id := m.store, start.ErrorID
+	return m.startLoad(fmt.Sprintf("Finding error %d", id), func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		e, err := store.ErrorByID(ctx, id)
+		if errors.Is(err, daqlog.ErrNotFound) {
+			return func(m *Model) tea.Cmd {
+				start.ErrorID = 0
+				note = fmt.Sprintf("Error %d not found", id)
+				return m.loadHutches(start.Hutch, start.Date, then)
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			start.Hutch = e.Hutch
+			start.Date = e.DateRef
+			return m.loadHutches(start.Hutch, start.Date, then)
+		}, nil
+	})
+}
+
+// applyStart applies the startup filters and selects the startup error,
+// clearing the filters if they hide it
+func (m *Model) applyStart(start viewState) {
+	m.applyView(start)
+	if start.ErrorID <= 0 {
+		return
+	}
+	if e := m.selectedError(); e == nil || e.ID != start.ErrorID {
+		m.selectInErrorList(start.ErrorID)
+	}
+	if e := m.selectedError(); e == nil || e.ID != start.ErrorID {
+		m.loadNote = fmt.Sprintf("Error %d is not in the %s %s error list", start.ErrorID, m.selectedHutch, m.selectedDate)
+	}
+}
+
+// loadHutches loads the hutch list, then continues into a hutch and date when
+// they are given (the command line's, or a history entry's). then runs once
+// the chain ends.
+func (m *Model) loadHutches(hutch, date string, then func(*Model)) tea.Cmd {
+	store := m.store
+	return m.startLoad("Loading hutches", func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		hutches, err := store.Hutches(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			m.hutches = hutches
+			if hutch == "" {
+				if then != nil {
+					then(m)
+				}
+				return nil
+			}
+			return m.loadDates(hutch, date, then)
+		}, nil
+	})
+}
+
+// loadDates loads a hutch's dates and opens the date picker. A non-empty date
+// goes straight on to its errors; otherwise then runs in the date picker.
+func (m *Model) loadDates(hutch, date string, then func(*Model)) tea.Cmd {
+	store := m.store
+	return m.startLoad("Loading dates for "+hutch, func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		dates, err := store.Dates(ctx, hutch)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			m.selectedHutch = hutch
+			for i, h := range m.hutches {
+				if h.Hutch == hutch {
+					m.hutchCursor = i
+					break
+				}
+			}
+			m.dates = dates
+			m.cursor = 0
+			for i, d := range dates {
+				if d.Date == date {
+					m.cursor = i
+					break
+				}
+			}
+			m.mode = ModeDatePicker
+			if date == "" {
+				if then != nil {
+					then(m)
+				}
+				return nil
+			}
+			return m.loadErrors(date, then)
+		}, nil
+	})
+}
+
+// loadErrors loads the selected hutch's errors for a date and opens the error
+// list, then runs then (e.g. to jump to a time) if it is set
+func (m *Model) loadErrors(date string, then func(*Model)) tea.Cmd {
+	store, hutch := m.store, m.selectedHutch
+	return m.startLoad(fmt.Sprintf("Loading %s %s", hutch, date), func(ctx context.Context) (func(*Model) tea.Cmd, error) {
+		errors, err := store.Errors(ctx, hutch, date)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *Model) tea.Cmd {
+			m.selectedDate = date
+			m.showErrors(errors)
+			if then != nil {
+				then(m)
+			}
+			return nil
+		}, nil
+	})
+}
+
+// showErrors replaces the error list with freshly loaded errors and resets
+// filters and cursors
+func (m *Model) showErrors(errors []daqlog.Error) {
+	m.allErrors = errors
+	m.filteredErrors = errors
+	m.levelFilter = ""
+	m.componentFilter = ""
+	m.buildGroups()
+	m.mode = ModeErrorList
+	m.focusedPanel = PanelGroups
+	m.groupCursor = 0
+	m.errorCursor = 0
+	m.groupOffset = 0
+	m.errorOffset = 0
+	m.updateContextPane()
+}
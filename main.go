@@ -2,76 +2,303 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
 	tea "github.com/charmbracelet/bubbletea"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "digest":
+			runDigest(os.Args[2:])
+			return
+		case "exporter":
+			runExporter(os.Args[2:])
+			return
+		case "gen-fixture":
+			runGenFixture(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		}
+	}
+
 	// Parse command line flags
-	dbPath := flag.String("db", "", "Path to daq_logs.db")
+	dbPath := flag.String("db", "", "Path to daq_logs.db; several as a comma-separated list or glob")
 	hutch := flag.String("hutch", "", "Hutch to browse (tmo, mfx, etc.)")
 	date := flag.String("date", "", "Date to browse (YYYY-MM-DD)")
-	time := flag.String("time", "", "Time to jump to (HH:MM)")
+	time := flag.String("time", "", "Time to jump to (HH:MM, in the display timezone)")
+	errorID := flag.Int("error-id", 0, "Open this error, on its hutch and date")
+	critical := flag.Bool("critical", false, "Show only critical errors")
+	component := flag.String("component", "", "Filter groups by component substring")
+	message := flag.String("message", "", "Filter the errors panel by message substring")
+	groupBy := flag.String("group-by", "component", "Group by component, host, type, file, signature or level")
+	bucket := flag.String("bucket", "1m", "Time bucket: all, 10s, 1m, 5m, 15m or 1h")
+	groupSort := flag.String("group-sort", "time", "Order of groups: time, count, crit or name")
+	errorSort := flag.String("error-sort", "time", "Order of errors in a group: time, line or level")
+	incidents := flag.Bool("incidents", false, "Show incidents in place of groups")
+	incidentGap := flag.Duration("incident-gap", defaultIncidentGap, "Quiet gap that separates incidents")
+	restore := flag.Bool("restore", false, "Restore the last session without asking")
+	tz := flag.String("tz", "pacific", "Display timezone: pacific, utc, local or an IANA name like Europe/Paris")
 	mouse := flag.Bool("mouse", false, "Enable mouse support")
 	flag.Parse()
 
-	// Find database
-	if *dbPath == "" {
-		// First check DAQ_LOG_DIR environment variable
-		if envPath := os.Getenv("DAQ_LOG_DIR"); envPath != "" {
-			*dbPath = envPath
-		} else {
-			// Try common locations
-			candidates := []string{
-				"daq_logs.db",
-				"../daq_logs.db",
-				filepath.Join(os.Getenv("HOME"), "proj-debug-daq/daq_logs.db"),
-			}
-			for _, c := range candidates {
-				if _, err := os.Stat(c); err == nil {
-					*dbPath = c
-					break
-				}
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+		os.Exit(1)
+	}
+
+	zone, err := daqlog.LoadZone(*tz)
+	if err != nil {
+		fail("unknown timezone %q: %v", *tz, err)
+	}
+
+	// The view to open: flags, or a daq:// link for the hutch, date and error
+	start := viewState{
+		Hutch:           *hutch,
+		Date:            *date,
+		ErrorID:         *errorID,
+		ComponentFilter: *component,
+		MessageFilter:   *message,
+		ShowIncidents:   *incidents,
+	}
+	switch args := flag.Args(); len(args) {
+	case 0:
+	case 1:
+		link, err := parseDeepLink(args[0])
+		if err != nil {
+			fail("%v", err)
+		}
+		start.Hutch, start.Date, start.ErrorID = link.Hutch, link.Date, link.ErrorID
+	default:
+		fail("unexpected arguments %q", args[1:])
+	}
+	if *critical {
+		start.LevelFilter = "C"
+	}
+	if start.GroupKey, err = parseOption(*groupBy, numGroupKeys); err != nil {
+		fail("--group-by: %v", err)
+	}
+	if start.TimeBucket, err = parseTimeBucket(*bucket); err != nil {
+		fail("--bucket: %v", err)
+	}
+	if start.GroupSort, err = parseOption(*groupSort, numGroupSorts); err != nil {
+		fail("--group-sort: %v", err)
+	}
+	if start.ErrorSort, err = parseOption(*errorSort, numErrorSorts); err != nil {
+		fail("--error-sort: %v", err)
+	}
+	if *incidentGap < minIncidentGap || *incidentGap > maxIncidentGap {
+		fail("--incident-gap must be between %v and %v", minIncidentGap, maxIncidentGap)
+	}
+	if *incidentGap != defaultIncidentGap {
+		start.IncidentGap = *incidentGap
+	}
+	if start.ErrorID > 0 || start.MessageFilter != "" {
+		start.FocusedPanel = PanelErrors
+	}
+
+	db := mustOpenDB(*dbPath, "daq-browser --db path/to/daq_logs.db [--hutch HUTCH] [--date YYYY-MM-DD] [--time HH:MM] [--error-id N] [filter flags] [--tz ZONE] [--mouse] [daq://hutch/date/error/ID]")
+	defer db.Close()
+
+	// Create model
+	store := mustNewStore(db)
+	m := NewModel(store, zone, start, *time)
+
+	// Unless told where to start, offer (or with --restore, reopen) the last
+	// session on the same databases
+	sessPath, err := sessionPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no session file: %v\n", err)
+	}
+	if sessPath != "" && start.Hutch == "" && start.ErrorID <= 0 {
+		sess, err := loadSession(sessPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring last session: %v\n", err)
+		}
+		if sess != nil && slices.Equal(sess.DB, store.Files()) {
+			if *restore {
+				m.initCmd = m.restoreSession(*sess)
+			} else {
+				m.sessionOffer = sess
 			}
 		}
 	}
 
-	if *dbPath == "" {
+	// Run Bubbletea program
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if *mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+		os.Exit(1)
+	}
+	if sess := final.(Model).session(); sess != nil && sessPath != "" {
+		if err := saveSession(sessPath, sess); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save session: %v\n", err)
+		}
+	}
+}
+
+// findDBPath returns the database path to use: the explicit path if given,
+// then DAQ_LOG_DIR, then the first common location that exists
+func findDBPath(dbPath string) string {
+	if dbPath != "" {
+		return dbPath
+	}
+
+	// First check DAQ_LOG_DIR environment variable
+	if envPath := os.Getenv("DAQ_LOG_DIR"); envPath != "" {
+		return envPath
+	}
+
+	// Try common locations
+	candidates := []string{
+		"daq_logs.db",
+		"../daq_logs.db",
+		filepath.Join(os.Getenv("HOME"), "proj-debug-daq/daq_logs.db"),
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// mustOpenDB finds and opens the database, exiting with a message on failure
+func mustOpenDB(dbPath, usage string) *sql.DB {
+	// Immutable mode: read-only, no locking
+	return mustOpenDBWith(dbPath, usage, "immutable=1")
+}
+
+// mustOpenLiveDB is like mustOpenDB but without the immutable flag, so that a
+// long-running process sees rows the ingester appends after startup
+func mustOpenLiveDB(dbPath, usage string) *sql.DB {
+	return mustOpenDBWith(dbPath, usage, "mode=ro")
+}
+
+// mustOpenDBWith finds and opens the databases with the given URI parameters.
+// Several databases are attached to one handle and read as one.
+func mustOpenDBWith(dbPath, usage, params string) *sql.DB {
+	// Find database
+	dbPath = findDBPath(dbPath)
+	if dbPath == "" {
 		fmt.Fprintln(os.Stderr, "Error: Could not find daq_logs.db")
-		fmt.Fprintln(os.Stderr, "Usage: daq-browser --db path/to/daq_logs.db [--hutch HUTCH] [--date YYYY-MM-DD] [--time HH:MM] [--mouse]")
+		fmt.Fprintln(os.Stderr, "Usage: "+usage)
+		os.Exit(1)
+	}
+	paths, err := expandDBPaths(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(paths) > maxDBs {
+		fmt.Fprintf(os.Stderr, "Error: %d databases given, but at most %d can be opened together\n", len(paths), maxDBs)
 		os.Exit(1)
 	}
 
-	// Open database in immutable mode (read-only, no locking)
-	db, err := sql.Open("sqlite3", "file:"+*dbPath+"?immutable=1")
+	db, err := daqlog.OpenSQLiteDB(params, paths...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
 		os.Exit(1)
 	}
-	defer db.Close()
 
 	// Verify database connection
 	if err := db.Ping(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
 		os.Exit(1)
 	}
+	return db
+}
 
-	// Create model
-	m := NewModel(db, *hutch, *date, *time)
+// maxDBs is the main database plus SQLite's default limit of 10 attached
+const maxDBs = 11
 
-	// Run Bubbletea program
-	opts := []tea.ProgramOption{tea.WithAltScreen()}
-	if *mouse {
-		opts = append(opts, tea.WithMouseCellMotion())
+// expandDBPaths splits a comma-separated --db value and expands globs, keeping
+// the order given. Each file is listed once. Commas that belong to an existing
+// file's name are kept: the longest run of parts naming a file is taken first.
+// An existing file is never read as a glob, whatever its name.
+func expandDBPaths(value string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	parts := strings.Split(value, ",")
+	for i := 0; i < len(parts); i++ {
+		p := strings.TrimSpace(parts[i])
+		for j := len(parts); j > i+1; j-- {
+			joined := strings.TrimSpace(strings.Join(parts[i:j], ","))
+			if _, err := os.Stat(joined); err == nil {
+				p, i = joined, j-1
+				break
+			}
+		}
+		if p == "" {
+			continue
+		}
+		matches := []string{p}
+		if _, err := os.Stat(p); err != nil && strings.ContainsAny(p, "*?[") {
+			var err error
+			matches, err = filepath.Glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %v", p, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no database matches %q", p)
+			}
+		}
+		for _, m := range matches {
+			key := m
+			if abs, err := filepath.Abs(m); err == nil {
+				key = abs
+			}
+			if !seen[key] {
+				seen[key] = true
+				paths = append(paths, m)
+			}
+		}
 	}
-	p := tea.NewProgram(m, opts...)
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no database given in %q", value)
+	}
+	return paths, nil
+}
+
+// mustNewStore checks the database schema and wraps it in a store, exiting
+// with a message naming the missing table or column if it cannot be read.
+// Warnings about unfamiliar layouts are printed and otherwise ignored.
+func mustNewStore(db *sql.DB) *daqlog.SQLiteStore {
+	store, err := daqlog.NewSQLiteStore(db)
+	var schemaErr *daqlog.SchemaError
+	if errors.As(err, &schemaErr) {
+		fmt.Fprintf(os.Stderr, "Error: incompatible database: %v\n", err)
 		os.Exit(1)
 	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading database schema: %v\n", err)
+		os.Exit(1)
+	}
+	for _, w := range store.Schema().Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return store
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandDBPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a,b.db", "c.db", "d.db", "run[1].db", "run2.db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, tt := range []struct {
+		value string
+		want  []string
+	}{
+		{path("a,b.db"), []string{path("a,b.db")}},
+		{path("c.db") + "," + path("a,b.db") + ", " + path("d.db"), []string{path("c.db"), path("a,b.db"), path("d.db")}},
+		{path("a,b.db") + "," + path("a,b.db"), []string{path("a,b.db")}},
+		{path("c.db") + "," + path("d*.db"), []string{path("c.db"), path("d.db")}},
+		{path("run[1].db"), []string{path("run[1].db")}}, // Exists, so not a pattern
+		{path("run[2].db"), []string{path("run2.db")}},
+		{path("new,file.db"), []string{path("new"), "file.db"}}, // Nothing to keep together
+	} {
+		got, err := expandDBPaths(tt.value)
+		if err != nil {
+			t.Errorf("%q: %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", " , ", path("none*.db")} {
+		if _, err := expandDBPaths(value); err == nil {
+			t.Errorf("%q: no error", value)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -20,6 +22,8 @@ const (
 	InputTimeJump
 	InputComponentFilter
 	InputMessageFilter
+	InputDiff
+	InputContextSearch
 )
 
 // Mode represents the current UI mode
@@ -29,6 +33,8 @@ const (
 	ModeHutchPicker Mode = iota
 	ModeDatePicker
 	ModeErrorList
+	ModeCorrelation
+	ModeDiff
 )
 
 // Panel focus for three-panel layout
@@ -40,11 +46,13 @@ const (
 	PanelContext              // Right panel: error context (scrollable)
 )
 
-// ErrorGroup represents errors grouped by (time, component)
+// ErrorGroup represents errors grouped by (time bucket, grouping key)
 type ErrorGroup struct {
-	Time      string  // "07:50"
-	Component string  // "teb0"
-	Errors    []Error // All errors in this group
+	Time     string         // "07:50" ("" when not bucketed by time)
+	Start    time.Time      // Start of the time bucket; zero when unbucketed or untimed
+	Key      string         // Grouping value, e.g. component "teb0" or host "drp-srcf-cmp001"
+	Errors   []daqlog.Error // All errors in this group
+	Incident *Incident      // Set when groups are incidents rather than (time, key)
 }
 
 // keyMap defines keyboard bindings
@@ -63,9 +71,49 @@ type keyMap struct {
 	Help         key.Binding
 	JumpTime     key.Binding
 	CriticalOnly key.Binding
-	Search      key.Binding
-	ClearFilter key.Binding
-	Zoom        key.Binding
+	Search       key.Binding
+	ClearFilter  key.Binding
+	Zoom         key.Binding
+
+	// Correlation view
+	Correlate      key.Binding
+	WindowWider    key.Binding
+	WindowNarrower key.Binding
+	AllHutches     key.Binding
+
+	// Root-cause candidates
+	NextRootCause key.Binding
+	PrevRootCause key.Binding
+
+	// Incidents
+	Incidents key.Binding
+
+	// Grouping
+	GroupKey   key.Binding
+	TimeBucket key.Binding
+	Sort       key.Binding
+
+	// Day-to-day diff
+	Diff key.Binding
+
+	// Split-screen comparison
+	Split      key.Binding
+	SwitchSide key.Binding
+	TimeLock   key.Binding
+	Zone       key.Binding
+
+	// Navigation history
+	HistoryBack    key.Binding
+	HistoryForward key.Binding
+	History        key.Binding
+
+	// Deep links
+	CopyLink    key.Binding
+	CopyCommand key.Binding
+
+	// Context search
+	NextMatch key.Binding
+	PrevMatch key.Binding
 }
 
 func defaultKeyMap() keyMap {
@@ -138,31 +186,131 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("z"),
 			key.WithHelp("z", "zoom"),
 		),
+		Correlate: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "correlate"),
+		),
+		WindowWider: key.NewBinding(
+			key.WithKeys("+", "="),
+			key.WithHelp("+", "wider window"),
+		),
+		WindowNarrower: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "narrower window"),
+		),
+		AllHutches: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "all hutches"),
+		),
+		NextRootCause: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "next root cause"),
+		),
+		PrevRootCause: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "prev root cause"),
+		),
+		Incidents: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "incidents"),
+		),
+		GroupKey: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "group by"),
+		),
+		TimeBucket: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "time bucket"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort panel"),
+		),
+		Diff: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "diff dates"),
+		),
+		Split: key.NewBinding(
+			key.WithKeys("|"),
+			key.WithHelp("|", "split screen"),
+		),
+		SwitchSide: key.NewBinding(
+			key.WithKeys("ctrl+w"),
+			key.WithHelp("ctrl+w", "switch side"),
+		),
+		TimeLock: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "time lock"),
+		),
+		Zone: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", "time zone"),
+		),
+		HistoryBack: key.NewBinding(
+			key.WithKeys("[", "alt+left"),
+			key.WithHelp("[", "back"),
+		),
+		HistoryForward: key.NewBinding(
+			key.WithKeys("]", "alt+right"),
+			key.WithHelp("]", "forward"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("'"),
+			key.WithHelp("'", "history"),
+		),
+		CopyLink: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy link"),
+		),
+		CopyCommand: key.NewBinding(
+			key.WithKeys("Y"),
+			key.WithHelp("Y", "copy command"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
 	}
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Down, k.JumpTime, k.CriticalOnly, k.Search, k.ClearFilter, k.Zoom, k.Quit}
+	return []key.Binding{k.Up, k.Down, k.JumpTime, k.CriticalOnly, k.Search, k.ClearFilter, k.Zoom, k.Correlate, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.PageUp, k.PageDown},
 		{k.Home, k.End, k.Enter, k.Back, k.Quit},
+		{k.Correlate, k.WindowWider, k.WindowNarrower, k.AllHutches},
+		{k.NextRootCause, k.PrevRootCause, k.Incidents, k.GroupKey, k.TimeBucket, k.Sort, k.Diff},
+		{k.Split, k.SwitchSide, k.TimeLock, k.Zone},
+		{k.HistoryBack, k.HistoryForward, k.History, k.CopyLink, k.CopyCommand},
+		{k.NextMatch, k.PrevMatch},
 	}
 }
 
 // Model is the main Bubbletea model
 type Model struct {
-	// Database
-	db *sql.DB
+	// Data source
+	store      daqlog.Store
+	contexts   *contextCache // Context lines fetched on demand
+	contextErr error         // Last context fetch failure
+	loading    *loadState    // Request in flight, nil when idle
+	loadNote   string        // Shown after a load is cancelled until the next key
+	spinner    spinner.Model
+	initCmd    tea.Cmd // First load, returned by Init
 
 	// Data
-	hutches        []HutchSummary
-	dates          []DateSummary
-	allErrors      []Error      // Full unfiltered list
-	filteredErrors []Error      // Currently visible (after filters)
-	groups         []ErrorGroup // Grouped by (time, component)
+	hutches        []daqlog.HutchSummary
+	dates          []daqlog.DateSummary
+	allErrors      []daqlog.Error    // Full unfiltered list
+	filteredErrors []daqlog.Error    // Currently visible (after filters)
+	groups         []ErrorGroup      // Grouped by (time bucket, grouping key)
+	rootCauses     map[int]RootCause // Burst root-cause candidates by error ID
 
 	// Navigation - three panel layout
 	mode         Mode
@@ -192,9 +340,67 @@ type Model struct {
 	timeInput       textinput.Model
 	filterInput     textinput.Model
 
+	// Grouping
+	groupKey   GroupKey   // Field used to group errors
+	timeBucket TimeBucket // Time bucket width for groups
+	groupSort  GroupSort  // Order of the groups panel
+	errorSort  ErrorSort  // Order of errors within a group
+
+	// Display timezone for every clock time shown (dates stay Pacific)
+	displayLoc *time.Location
+	zones      []*time.Location // Zones the Z key cycles through
+	zoneIdx    int
+
+	// Incident view (alternative to time/component groups)
+	showIncidents bool
+	incidentGap   time.Duration // Quiet gap that separates incidents
+
+	// Correlation view around a selected error
+	corrAnchor     *daqlog.Error     // Error the window is centred on
+	corrWindow     int               // Half-width of the window in seconds
+	corrAllHutches bool              // Include other hutches
+	correlated     []CorrelatedError // Errors inside the window, by offset
+	corrCursor     int
+	corrOffset     int
+	corrExtra      []daqlog.Error // Errors beyond the selected hutch's date
+	corrExtraHutch string         // Selected hutch corrExtra was loaded for
+	corrExtraDates []string       // Pacific dates in corrExtra, the selected one first
+	corrExtraAll   bool           // Whether corrExtra has the other hutches
+
+	// Day-to-day signature diff
+	diffInput         textinput.Model
+	diffDefaultTarget string // Target range when the input names only a baseline
+	diffErr           string // Parse error shown in the diff input dialog
+	diffBase          DateRange
+	diffTarget        DateRange
+	diffs             []SignatureDiff
+	diffCursor        int
+	diffOffset        int
+	diffShowSame      bool
+	diffReturnMode    Mode        // Mode the diff was opened from
+	diffSaved         *diffReturn // Error list to restore after drilling in
+	diffDrillLabel    string      // Set while browsing a signature's errors
+
+	// Split-screen comparison
+	split       *splitState // Set on the top-level model while split
+	inSplit     bool        // Set on each side of a split
+	splitActive bool        // Side currently receiving keys
+
+	// Back/forward navigation history
+	history       *navHistory
+	showHistory   bool // History list open
+	historyCursor int  // Entry selected in the history list
+
+	// Last session, offered in the hutch picker until a key is pressed
+	sessionOffer *session
+
 	// Viewport for context pane
 	viewport viewport.Model
 
+	// Search inside the context pane
+	search      contextSearch
+	searchInput textinput.Model
+
 	// Help
 	help     help.Model
 	keys     keyMap
@@ -211,8 +417,10 @@ type Model struct {
 	err      error
 }
 
-// NewModel creates a new model
-func NewModel(db *sql.DB, initialHutch, initialDate, initialTime string) Model {
+// NewModel creates a new model that opens start: its hutch and date, or the
+// error start.ErrorID wherever it is, with start's filters and grouping.
+// initialTime (HH:MM) then moves the selection.
+func NewModel(store daqlog.Store, zone *time.Location, start viewState, initialTime string) Model {
 	h := help.New()
 	h.ShowAll = false
 
@@ -228,71 +436,49 @@ func NewModel(db *sql.DB, initialHutch, initialDate, initialTime string) Model {
 	fi.CharLimit = 30
 	fi.Width = 25
 
-	m := Model{
-		db:          db,
-		mode:        ModeHutchPicker,
-		keys:        defaultKeyMap(),
-		help:        h,
-		pageSize:    15,
-		timeInput:   ti,
-		filterInput: fi,
-		inputMode:   InputNone,
-	}
+	// Initialize context search input
+	si := textinput.New()
+	si.Prompt = ""
+	si.CharLimit = 60
+	si.Width = 20
 
-	// Load hutches
-	hutches, err := GetHutchesWithErrors(db)
-	if err != nil {
-		m.err = err
-		return m
-	}
-	m.hutches = hutches
-
-	// If initial hutch provided, skip to date picker
-	if initialHutch != "" {
-		m.selectedHutch = initialHutch
-		// Find hutch index
-		for i, h := range hutches {
-			if h.Hutch == initialHutch {
-				m.hutchCursor = i
-				break
-			}
-		}
+	// Initialize diff input
+	di := textinput.New()
+	di.Placeholder = "YYYY-MM-DD[..YYYY-MM-DD] [vs ...]"
+	di.CharLimit = 60
+	di.Width = 34
 
-		// Load dates for this hutch
-		dates, err := GetDatesWithErrors(db, initialHutch)
-		if err != nil {
-			m.err = err
-			return m
-		}
-		m.dates = dates
-		m.mode = ModeDatePicker
-
-		// If initial date also provided, load errors directly
-		if initialDate != "" {
-			m.selectedDate = initialDate
-			errors, err := LoadErrors(db, initialHutch, initialDate)
-			if err != nil {
-				m.err = err
-				return m
-			}
-			m.allErrors = errors
-			m.filteredErrors = errors
-			m.mode = ModeErrorList
-
-			// Pin to initial time if provided
-			if initialTime != "" && len(errors) > 0 {
-				m.cursor = FindNearestErrorIndex(errors, initialTime)
-				// Adjust page offset to show cursor
-				m.pageOffset = (m.cursor / m.pageSize) * m.pageSize
-			}
-		}
+	m := Model{
+		store:         store,
+		mode:          ModeHutchPicker,
+		keys:          defaultKeyMap(),
+		help:          h,
+		pageSize:      15,
+		timeInput:     ti,
+		filterInput:   fi,
+		searchInput:   si,
+		inputMode:     InputNone,
+		timeBucket:    start.TimeBucket,
+		groupKey:      start.GroupKey,
+		groupSort:     start.GroupSort,
+		errorSort:     start.ErrorSort,
+		incidentGap:   start.IncidentGap,
+		showIncidents: start.ShowIncidents,
+		diffInput:     di,
+		spinner:       newSpinner(),
+		contexts:      newContextCache(contextCacheSize),
+		zones:         displayZones(zone),
+		history:       &navHistory{},
 	}
+	m.displayLoc = m.zones[0]
 
+	// Loading starts from Init; the initial hutch, date and time chain on
+	m.initCmd = m.loadStart(start, initialTime)
 	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return m.initCmd
 }
 
 // updateContextPane updates the viewport with current error's context
@@ -307,14 +493,19 @@ func (m *Model) updateContextPane() {
 		return
 	}
 
-	e := errors[m.errorCursor]
-	content := formatContext(e, m.viewport.Width)
-	m.viewport.SetContent(content)
-	m.viewport.GotoTop()
+	e, loaded := m.withContext(errors[m.errorCursor])
+	content := formatContext(e, m.viewport.Width, m.displayLoc)
+	if !loaded {
+		content += "\n" + m.contextPending()
+	}
+	if rc, ok := m.rootCauses[e.ID]; ok {
+		content = criticalStyle.Render("Root cause? ") + wrapText(rootCauseSummary(rc), m.viewport.Width-16) + "\n" + content
+	}
+	m.setContextContent(content)
 }
 
 // selectedError returns the currently selected error
-func (m *Model) selectedError() *Error {
+func (m *Model) selectedError() *daqlog.Error {
 	errors := m.getFilteredGroupErrors()
 	if len(errors) == 0 || m.errorCursor >= len(errors) {
 		return nil
@@ -322,8 +513,9 @@ func (m *Model) selectedError() *Error {
 	return &errors[m.errorCursor]
 }
 
-// formatContext formats error context for display with word wrapping
-func formatContext(e Error, width int) string {
+// formatContext formats error context for display with word wrapping, with the
+// error's time in loc
+func formatContext(e daqlog.Error, width int, loc *time.Location) string {
 	var sb strings.Builder
 
 	// Content width (account for padding/borders)
@@ -333,6 +525,10 @@ func formatContext(e Error, width int) string {
 	}
 
 	// Header
+	sb.WriteString(contextHeaderStyle.Render("Time: "))
+	sb.WriteString(errorTimeLabel(e, loc))
+	sb.WriteString("\n")
+
 	sb.WriteString(contextHeaderStyle.Render("Component: "))
 	sb.WriteString(e.Component)
 	sb.WriteString(" @ ")
@@ -345,6 +541,12 @@ func formatContext(e Error, width int) string {
 	sb.WriteString(fmt.Sprintf("%d", e.LineNumber))
 	sb.WriteString("\n")
 
+	if e.Source != "" {
+		sb.WriteString(contextHeaderStyle.Render("DB: "))
+		sb.WriteString(wrapText(e.Source, contentWidth-4))
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString(contextHeaderStyle.Render("Type: "))
 	sb.WriteString(e.ErrorType)
 	sb.WriteString("  ")
@@ -363,7 +565,7 @@ func formatContext(e Error, width int) string {
 			} else {
 				sb.WriteString("     ")
 			}
-			sb.WriteString(wrapText(line, contentWidth-6))
+			sb.WriteString(highlightLog(wrapText(line, contentWidth-6)))
 			sb.WriteString("\n")
 		}
 	}
@@ -379,7 +581,7 @@ func formatContext(e Error, width int) string {
 		for i, line := range lines {
 			lineNum := e.LineNumber + i + 1
 			sb.WriteString(lineNumberStyle.Render(fmt.Sprintf("%4d ", lineNum)))
-			sb.WriteString(wrapText(line, contentWidth-6))
+			sb.WriteString(highlightLog(wrapText(line, contentWidth-6)))
 			sb.WriteString("\n")
 		}
 	}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// reportBinsPerHour sets the timeline resolution (15-minute bars)
+const reportBinsPerHour = 4
+
+// runReport implements the "report" subcommand: a self-contained HTML page for
+// one hutch and date that can be attached to an e-logbook entry or a ticket
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Path to daq_logs.db; several as a comma-separated list or glob")
+	hutch := fs.String("hutch", "", "Hutch to report on (tmo, mfx, etc.)")
+	date := fs.String("date", "", "Pacific date to report on (YYYY-MM-DD)")
+	out := fs.String("out", "", "Output file (default stdout)")
+	fs.Parse(args)
+
+	usage := "daq-browser report --hutch HUTCH --date YYYY-MM-DD [--db path/to/daq_logs.db] [--out report.html]"
+	if *hutch == "" || *date == "" {
+		fmt.Fprintln(os.Stderr, "Error: --hutch and --date are required")
+		fmt.Fprintln(os.Stderr, "Usage: "+usage)
+		os.Exit(1)
+	}
+	if _, err := time.Parse("2006-01-02", *date); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid date %q (want YYYY-MM-DD)\n", *date)
+		os.Exit(1)
+	}
+
+	db := mustOpenDB(*dbPath, usage)
+	defer db.Close()
+
+	// The report shows every error's context, so load it all up front
+	store := mustNewStore(db)
+	errors, err := store.Errors(context.Background(), *hutch, *date)
+	if err == nil {
+		err = daqlog.FillContexts(context.Background(), store, errors)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading errors: %v\n", err)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *out, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeReport(bw, *hutch, *date, errors); err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d errors in %s\n", len(errors), *out)
+	}
+}
+
+// reportCount is one row of a count table
+type reportCount struct {
+	Name    string
+	Count   int
+	Percent float64 // Relative to the largest row, for the bar width
+}
+
+// reportBin is one bar of the timeline
+type reportBin struct {
+	Label     string // Pacific start time (HH:MM)
+	Axis      string // Axis label, set every few hours
+	Count     int
+	Criticals int
+	Height    float64 // Percent of the tallest bar
+}
+
+// reportError is an error as rendered in the report
+type reportError struct {
+	daqlog.Error
+	Time      string // Pacific HH:MM:SS, or "??:??:??"
+	RootCause string // Root-cause candidate description, if any
+	Badge     string
+	Context   []reportLine
+}
+
+// reportLine is a numbered line of an error's context
+type reportLine struct {
+	Number  string // Blank when the line number would be before the file start
+	Text    string
+	IsError bool
+}
+
+// reportContext numbers the context lines around an error the same way as the
+// TUI's context pane
+func reportContext(e daqlog.Error) []reportLine {
+	var lines []reportLine
+	if e.ContextBefore != "" {
+		before := strings.Split(e.ContextBefore, "\n")
+		start := e.LineNumber - len(before)
+		for i, text := range before {
+			l := reportLine{Text: text}
+			if start+i > 0 {
+				l.Number = fmt.Sprintf("%d", start+i)
+			}
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, reportLine{Number: fmt.Sprintf("%d", e.LineNumber), Text: e.Message, IsError: true})
+	if e.ContextAfter != "" {
+		for i, text := range strings.Split(e.ContextAfter, "\n") {
+			lines = append(lines, reportLine{Number: fmt.Sprintf("%d", e.LineNumber+i+1), Text: text})
+		}
+	}
+	return lines
+}
+
+// reportGroup is a groups-panel group as rendered in the report
+type reportGroup struct {
+	Label     string
+	Errors    []reportError
+	Criticals int
+	Badge     string
+}
+
+// reportData is the template input
+type reportData struct {
+	Hutch       string
+	Date        string
+	Generated   string
+	Total       int
+	Criticals   int
+	First       string
+	Last        string
+	ByComponent []reportCount
+	ByLevel     []reportCount
+	Timeline    []reportBin
+	Untimed     int
+	Groups      []reportGroup
+}
+
+// writeReport renders the HTML report for a day's errors
+func writeReport(w io.Writer, hutch, date string, errors []daqlog.Error) error {
+	data := reportData{
+		Hutch:       hutch,
+		Date:        date,
+		Generated:   daqlog.UTCToPacific(time.Now().UTC()).Format("2006-01-02 15:04 MST"),
+		Total:       len(errors),
+		Criticals:   countCriticals(errors),
+		ByComponent: reportCounts(errors, func(e daqlog.Error) string { return e.Component }),
+		ByLevel:     reportCounts(errors, func(e daqlog.Error) string { return e.LogLevel }),
+	}
+	data.Timeline, data.Untimed = reportTimeline(errors)
+	if len(errors) > 0 {
+		data.First = daqlog.ErrorClock(errors[0], "15:04:05")
+		data.Last = daqlog.ErrorClock(errors[len(errors)-1], "15:04:05")
+	}
+
+	// Same grouping as the TUI's default groups panel
+	rootCauses := findRootCauses(errors)
+	for _, g := range groupErrors(errors, GroupByComponent, Bucket1m, daqlog.PacificLoc) {
+		rg := reportGroup{Label: groupLabel(g), Criticals: countCriticals(g.Errors)}
+		kind := 0
+		for _, e := range g.Errors {
+			re := reportError{Error: e, Time: "??:??:??", Context: reportContext(e)}
+			if t, ok := daqlog.ErrorTime(e); ok {
+				re.Time = daqlog.UTCToPacific(t).Format("15:04:05")
+			}
+			if rc, ok := rootCauses[e.ID]; ok {
+				re.Badge = rootCauseBadge(rc.Kind)
+				re.RootCause = rootCauseSummary(rc)
+				kind |= rc.Kind
+			}
+			rg.Errors = append(rg.Errors, re)
+		}
+		rg.Badge = rootCauseBadge(kind)
+		data.Groups = append(data.Groups, rg)
+	}
+
+	return reportTemplate.Execute(w, data)
+}
+
+// reportCounts counts errors by a field, largest first
+func reportCounts(errors []daqlog.Error, value func(daqlog.Error) string) []reportCount {
+	counts := make(map[string]int)
+	for _, e := range errors {
+		counts[value(e)]++
+	}
+
+	var out []reportCount
+	peak := 0
+	for name, n := range counts {
+		out = append(out, reportCount{Name: name, Count: n})
+		peak = max(peak, n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	for i := range out {
+		out[i].Percent = 100 * float64(out[i].Count) / float64(peak)
+	}
+	return out
+}
+
+// reportTimeline bins errors by Pacific clock time across the day. Errors
+// without a usable time are counted separately.
+func reportTimeline(errors []daqlog.Error) ([]reportBin, int) {
+	bins := make([]reportBin, 24*reportBinsPerHour)
+	for i := range bins {
+		minutes := i * 60 / reportBinsPerHour
+		bins[i].Label = fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+		if i%(3*reportBinsPerHour) == 0 {
+			bins[i].Axis = bins[i].Label
+		}
+	}
+
+	untimed := 0
+	for _, e := range errors {
+		t, ok := daqlog.ErrorTime(e)
+		if !ok {
+			untimed++
+			continue
+		}
+		p := daqlog.UTCToPacific(t)
+		b := &bins[p.Hour()*reportBinsPerHour+p.Minute()*reportBinsPerHour/60]
+		b.Count++
+		if e.LogLevel == "C" {
+			b.Criticals++
+		}
+	}
+
+	peak := 0
+	for _, b := range bins {
+		peak = max(peak, b.Count)
+	}
+	if peak > 0 {
+		for i := range bins {
+			bins[i].Height = 100 * float64(bins[i].Count) / float64(peak)
+		}
+	}
+	return bins, untimed
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"levelClass": func(level string) string {
+		if level == "C" {
+			return "crit"
+		}
+		return "err"
+	},
+	"percent": func(part, whole int) float64 {
+		if whole == 0 {
+			return 0
+		}
+		return 100 * float64(part) / float64(whole)
+	},
+}).Parse(reportHTML))
+
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>DAQ errors: {{.Hutch}} {{.Date}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #777; margin-bottom: 1.5em; }
+.summary span { margin-right: 2em; }
+.crit { color: #c00; font-weight: bold; }
+.err { color: #d70; }
+.dim { color: #999; }
+.tables { display: flex; gap: 3em; flex-wrap: wrap; }
+table.counts td { padding: 1px 8px; }
+table.counts td.n { text-align: right; }
+.bar { background: #7aa7d8; height: 0.8em; min-width: 1px; }
+.timeline { display: flex; align-items: flex-end; height: 120px; border-bottom: 1px solid #999; gap: 1px; }
+.timeline .bin { flex: 1; position: relative; height: 100%; display: flex; align-items: flex-end; }
+.timeline .fill { width: 100%; background: #7aa7d8; display: flex; flex-direction: column; justify-content: flex-end; }
+.timeline .critfill { background: #c00; width: 100%; }
+.axis { display: flex; font-size: 0.75em; color: #777; }
+.axis { gap: 1px; }
+.axis span { flex: 1; overflow: visible; white-space: nowrap; width: 0; }
+details.group { border-top: 1px solid #ddd; padding: 4px 0; }
+details.group > summary { cursor: pointer; font-family: monospace; }
+details.error { margin: 2px 0 2px 2em; }
+details.error > summary { cursor: pointer; font-family: monospace; white-space: pre-wrap; }
+pre.context { background: #f6f6f6; padding: 6px; margin: 4px 0 8px 0; overflow-x: auto; }
+pre.context .line { background: #fde8a0; }
+pre.context .num { color: #999; }
+.badge { color: #c60; }
+</style>
+</head>
+<body>
+<h1>DAQ errors: {{.Hutch}} {{.Date}}</h1>
+<div class="meta">Times are US/Pacific. Generated {{.Generated}}.</div>
+
+<div class="summary">
+<span><b>{{.Total}}</b> errors</span>
+<span class="crit">{{.Criticals}} critical</span>
+{{if .First}}<span>first {{.First}}</span><span>last {{.Last}}</span>{{end}}
+</div>
+
+{{if .Total}}
+<h2>Timeline</h2>
+<div class="timeline">
+{{range .Timeline}}<div class="bin" title="{{.Label}}: {{.Count}} errors, {{.Criticals}} critical"><div class="fill" style="height: {{printf "%.1f" .Height}}%">{{if .Criticals}}<div class="critfill" style="height: {{printf "%.1f" (percent .Criticals .Count)}}%"></div>{{end}}</div></div>{{end}}
+</div>
+<div class="axis">{{range .Timeline}}<span>{{.Axis}}</span>{{end}}</div>
+{{if .Untimed}}<p class="dim">{{.Untimed}} errors have no usable timestamp and are not shown on the timeline.</p>{{end}}
+
+<div class="tables">
+<div>
+<h2>By component</h2>
+<table class="counts">
+{{range .ByComponent}}<tr><td>{{.Name}}</td><td class="n">{{.Count}}</td><td style="width: 200px"><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+</div>
+<div>
+<h2>By level</h2>
+<table class="counts">
+{{range .ByLevel}}<tr><td class="{{levelClass .Name}}">{{.Name}}</td><td class="n">{{.Count}}</td><td style="width: 200px"><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+</div>
+</div>
+
+<h2>Groups</h2>
+<p class="dim">Grouped by minute and component, as in the browser. ★ marks the first error of a burst, ! the first critical.</p>
+{{range .Groups}}<details class="group">
+<summary>{{.Label}} ({{len .Errors}}){{if .Criticals}} <span class="crit">{{.Criticals}} critical</span>{{end}}{{if .Badge}} <span class="badge">{{.Badge}}</span>{{end}}</summary>
+{{range .Errors}}<details class="error">
+<summary><span class="dim">{{.Time}}</span> <span class="{{levelClass .LogLevel}}">[{{.LogLevel}}]</span> {{.Host}} {{.Message}}{{if .Badge}} <span class="badge">{{.Badge}}</span>{{end}}</summary>
+{{if .RootCause}}<div class="badge">Root cause? {{.RootCause}}</div>{{end}}
+<div class="dim">{{.FilePath}}:{{.LineNumber}} ({{.ErrorType}})</div>
+<pre class="context">{{range .Context}}<span class="{{if .IsError}}line{{else}}ctx{{end}}"><span class="num">{{printf "%5s" .Number}}</span> {{.Text}}</span>
+{{end}}</pre>
+</details>
+{{end}}</details>
+{{end}}
+{{else}}
+<p>No errors recorded for this hutch and date.</p>
+{{end}}
+</body>
+</html>
+`
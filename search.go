@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// searchMatch is a hit of the context search: a range of cells on a line of
+// the context pane's content
+type searchMatch struct {
+	line, start, end int
+}
+
+// contextSearch is the search inside the context pane. It is kept while the
+// selection moves, so the same text can be looked for in each error.
+type contextSearch struct {
+	query   string
+	text    string        // Pane content without the highlighting
+	matches []searchMatch // Hits of query in text
+	current int           // Match scrolled to, -1 before n is pressed
+
+	// Restored when the search input is cancelled
+	prevQuery  string
+	prevOffset int
+}
+
+// findMatches returns the case-insensitive hits of query in text, which may
+// carry styling
+func findMatches(text, query string) []searchMatch {
+	if query == "" {
+		return nil
+	}
+	q := strings.ToLower(query)
+	var matches []searchMatch
+	for i, line := range strings.Split(text, "\n") {
+		plain := strings.ToLower(ansi.Strip(line))
+		for from := 0; ; {
+			j := strings.Index(plain[from:], q)
+			if j < 0 {
+				break
+			}
+			start := from + j
+			from = start + len(q)
+			matches = append(matches, searchMatch{
+				line:  i,
+				start: ansi.StringWidth(plain[:start]),
+				end:   ansi.StringWidth(plain[:from]),
+			})
+		}
+	}
+	return matches
+}
+
+// highlightMatches styles the matches in text, the current one apart from the
+// rest
+func highlightMatches(text string, matches []searchMatch, current int) string {
+	if len(matches) == 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	byLine := make(map[int][]lipgloss.Range)
+	for i, hit := range matches {
+		style := searchMatchStyle
+		if i == current {
+			style = searchCurrentStyle
+		}
+		byLine[hit.line] = append(byLine[hit.line], lipgloss.NewRange(hit.start, hit.end, style))
+	}
+	for i, ranges := range byLine {
+		lines[i] = lipgloss.StyleRanges(lines[i], ranges...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setContextContent shows content in the context pane with the search hits
+// highlighted, scrolled to the top
+func (m *Model) setContextContent(content string) {
+	m.search.text = content
+	m.search.matches = findMatches(content, m.search.query)
+	m.search.current = -1
+	m.viewport.SetContent(highlightMatches(content, m.search.matches, -1))
+	m.viewport.GotoTop()
+}
+
+// gotoMatch scrolls the context pane to match i, keeping two lines above it
+// in view
+func (m *Model) gotoMatch(i int) {
+	m.search.current = i
+	m.viewport.SetContent(highlightMatches(m.search.text, m.search.matches, i))
+	if i >= 0 {
+		m.viewport.SetYOffset(max(m.search.matches[i].line-2, 0))
+	}
+}
+
+// stepMatch moves to the next (+1) or previous (-1) match, wrapping around
+func (m *Model) stepMatch(delta int) {
+	n := len(m.search.matches)
+	if n == 0 {
+		return
+	}
+	i := m.search.current + delta
+	if m.search.current < 0 && delta < 0 {
+		i = n - 1
+	}
+	m.gotoMatch((i + n) % n)
+}
+
+// startContextSearch opens the search input in the context pane
+func (m Model) startContextSearch() (Model, tea.Cmd) {
+	m.inputMode = InputContextSearch
+	m.search.prevQuery = m.search.query
+	m.search.prevOffset = m.viewport.YOffset
+	m.searchInput.SetValue(m.search.query)
+	m.searchInput.CursorEnd()
+	m.searchInput.Focus()
+	return m, textinput.Blink
+}
+
+// updateSearchInput searches as the query is typed, from where the pane was
+// scrolled when the search started. Enter keeps the query, esc restores the
+// previous one.
+func (m Model) updateSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.inputMode = InputNone
+		m.searchInput.Blur()
+		m.search.query = m.search.prevQuery
+		m.search.matches = findMatches(m.search.text, m.search.query)
+		m.gotoMatch(-1)
+		m.viewport.SetYOffset(m.search.prevOffset)
+		return m, nil
+	case tea.KeyEnter:
+		m.inputMode = InputNone
+		m.searchInput.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	if q := m.searchInput.Value(); q != m.search.query {
+		m.search.query = q
+		m.search.matches = findMatches(m.search.text, q)
+		m.gotoMatch(-1)
+		m.viewport.SetYOffset(m.search.prevOffset)
+		for i, hit := range m.search.matches {
+			if hit.line >= m.search.prevOffset {
+				m.gotoMatch(i)
+				break
+			}
+		}
+	}
+	return m, cmd
+}
+
+// searchHeader renders the search line at the top of the context pane: the
+// input while it is open, otherwise the query and its match counter
+func (m Model) searchHeader() string {
+	if m.inputMode == InputContextSearch {
+		if m.search.query == "" {
+			return "/" + m.searchInput.View()
+		}
+		return "/" + m.searchInput.View() + "  " + m.matchCounter()
+	}
+	if m.search.query == "" {
+		return ""
+	}
+	return filterStyle.Render("/"+m.search.query) + "  " + m.matchCounter()
+}
+
+// matchCounter reads "3/12" once a match is selected, otherwise the count
+func (m Model) matchCounter() string {
+	n := len(m.search.matches)
+	switch {
+	case n == 0:
+		return criticalStyle.Render("no matches")
+	case m.search.current >= 0:
+		return helpStyle.Render(fmt.Sprintf("%d/%d", m.search.current+1, n))
+	case n == 1:
+		return helpStyle.Render("1 match")
+	}
+	return helpStyle.Render(fmt.Sprintf("%d matches", n))
+}
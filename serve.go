@@ -0,0 +1,353 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// Pagination limits for /api/errors
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// runServe implements the "serve" subcommand: a read-only JSON API over the database
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dbPath := fs.String("db", "", "Path to daq_logs.db; several as a comma-separated list or glob")
+	listen := fs.String("listen", ":8080", "Address to listen on")
+	fs.Parse(args)
+
+	db := mustOpenDB(*dbPath, "daq-browser serve --db path/to/daq_logs.db [--listen :8080]")
+	defer db.Close()
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           newAPIHandler(mustNewStore(db)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving DAQ error API on %s", *listen)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running server: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// apiServer holds the dependencies of the JSON API handlers
+type apiServer struct {
+	store daqlog.Store
+}
+
+// newAPIHandler returns the JSON API routes. It is separate from runServe so the
+// API can be exercised with httptest and an in-memory store.
+func newAPIHandler(store daqlog.Store) http.Handler {
+	s := &apiServer{store: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/hutches", s.handleHutches)
+	mux.HandleFunc("GET /api/hutches/{hutch}/dates", s.handleDates)
+	mux.HandleFunc("GET /api/errors", s.handleErrors)
+	mux.HandleFunc("GET /api/errors/{id}", s.handleError)
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	return mux
+}
+
+// apiError is the JSON form of an Error
+type apiError struct {
+	ID            int    `json:"id"`
+	Hutch         string `json:"hutch"`
+	Date          string `json:"date"`                    // Pacific date (YYYY-MM-DD)
+	TimestampUTC  string `json:"timestamp_utc,omitempty"` // RFC 3339, when known
+	TimePacific   string `json:"time_pacific,omitempty"`  // HH:MM:SS, when known
+	Component     string `json:"component"`
+	Host          string `json:"host"`
+	Level         string `json:"level"`
+	ErrorType     string `json:"error_type"`
+	Message       string `json:"message"`
+	LineNumber    int    `json:"line_number"`
+	FilePath      string `json:"file_path"`
+	Source        string `json:"source,omitempty"` // Database file, when several are served
+	ContextBefore string `json:"context_before,omitempty"`
+	ContextAfter  string `json:"context_after,omitempty"`
+}
+
+// toAPIError converts an Error, optionally including its context lines
+func toAPIError(e daqlog.Error, withContext bool) apiError {
+	a := apiError{
+		ID:         e.ID,
+		Hutch:      e.Hutch,
+		Date:       e.DateRef,
+		Component:  e.Component,
+		Host:       e.Host,
+		Level:      e.LogLevel,
+		ErrorType:  e.ErrorType,
+		Message:    e.Message,
+		LineNumber: e.LineNumber,
+		FilePath:   e.FilePath,
+		Source:     e.Source,
+	}
+	if t, ok := daqlog.ErrorTime(e); ok {
+		a.TimestampUTC = t.Format(time.RFC3339)
+		a.TimePacific = daqlog.UTCToPacific(t).Format("15:04:05")
+	}
+	if withContext {
+		a.ContextBefore = e.ContextBefore
+		a.ContextAfter = e.ContextAfter
+	}
+	return a
+}
+
+func (s *apiServer) handleHutches(w http.ResponseWriter, r *http.Request) {
+	hutches, err := s.store.Hutches(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type hutchJSON struct {
+		Hutch  string `json:"hutch"`
+		Files  int    `json:"files"`
+		Errors int    `json:"errors"`
+	}
+	out := []hutchJSON{}
+	for _, h := range hutches {
+		out = append(out, hutchJSON{h.Hutch, h.FileCount, h.ErrorCount})
+	}
+	writeJSON(w, r, out)
+}
+
+func (s *apiServer) handleDates(w http.ResponseWriter, r *http.Request) {
+	dates, err := s.store.Dates(r.Context(), r.PathValue("hutch"))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type dateJSON struct {
+		Date   string `json:"date"`
+		Files  int    `json:"files"`
+		Errors int    `json:"errors"`
+	}
+	out := []dateJSON{}
+	for _, d := range dates {
+		out = append(out, dateJSON{d.Date, d.FileCount, d.ErrorCount})
+	}
+	writeJSON(w, r, out)
+}
+
+// loadFilteredErrors loads errors for the hutch and date query parameters and
+// applies the same level/component/message filters as the TUI
+func (s *apiServer) loadFilteredErrors(r *http.Request) ([]daqlog.Error, int, error) {
+	q := r.URL.Query()
+	hutch, date := q.Get("hutch"), q.Get("date")
+	if hutch == "" || date == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("hutch and date parameters are required")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", date)
+	}
+
+	all, err := s.store.Errors(r.Context(), hutch, date)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	level := strings.ToUpper(q.Get("level"))
+	var filtered []daqlog.Error
+	for _, e := range all {
+		if matchesFilters(e, level, q.Get("component"), q.Get("message")) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, http.StatusOK, nil
+}
+
+func (s *apiServer) handleErrors(w http.ResponseWriter, r *http.Request) {
+	filtered, status, err := s.loadFilteredErrors(r)
+	if err != nil {
+		writeAPIError(w, status, err)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := intParam(q.Get("limit"), defaultPageLimit)
+	if err != nil || limit < 1 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", q.Get("limit")))
+		return
+	}
+	limit = min(limit, maxPageLimit)
+	offset, err := intParam(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid offset %q", q.Get("offset")))
+		return
+	}
+	withContext := q.Get("context") == "1" || q.Get("context") == "true"
+
+	start := min(offset, len(filtered))
+	rows := filtered[start:min(start+limit, len(filtered))]
+	if withContext {
+		if err := daqlog.FillContexts(r.Context(), s.store, rows); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	page := []apiError{}
+	for _, e := range rows {
+		page = append(page, toAPIError(e, withContext))
+	}
+
+	resp := struct {
+		Total      int        `json:"total"`
+		Offset     int        `json:"offset"`
+		Limit      int        `json:"limit"`
+		NextOffset *int       `json:"next_offset"`
+		Errors     []apiError `json:"errors"`
+	}{Total: len(filtered), Offset: offset, Limit: limit, Errors: page}
+	if next := offset + limit; next < len(filtered) {
+		resp.NextOffset = &next
+	}
+	writeJSON(w, r, resp)
+}
+
+func (s *apiServer) handleError(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid error id %q", r.PathValue("id")))
+		return
+	}
+
+	e, err := s.store.ErrorByID(r.Context(), id)
+	if errors.Is(err, daqlog.ErrNotFound) {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, toAPIError(*e, true))
+}
+
+func (s *apiServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	filtered, status, err := s.loadFilteredErrors(r)
+	if err != nil {
+		writeAPIError(w, status, err)
+		return
+	}
+
+	type countJSON struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	counts := func(value func(daqlog.Error) string) []countJSON {
+		m := make(map[string]int)
+		for _, e := range filtered {
+			m[value(e)]++
+		}
+		out := []countJSON{}
+		for k, v := range m {
+			out = append(out, countJSON{k, v})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Count != out[j].Count {
+				return out[i].Count > out[j].Count
+			}
+			return out[i].Name < out[j].Name
+		})
+		return out
+	}
+
+	resp := struct {
+		Total       int         `json:"total"`
+		Criticals   int         `json:"criticals"`
+		First       string      `json:"first,omitempty"` // Pacific HH:MM:SS of the earliest timed error
+		Last        string      `json:"last,omitempty"`
+		Untimed     int         `json:"untimed"` // Errors without a usable time
+		ByLevel     []countJSON `json:"by_level"`
+		ByComponent []countJSON `json:"by_component"`
+		ByHost      []countJSON `json:"by_host"`
+		ByErrorType []countJSON `json:"by_error_type"`
+	}{
+		Total:       len(filtered),
+		Criticals:   countCriticals(filtered),
+		ByLevel:     counts(func(e daqlog.Error) string { return e.LogLevel }),
+		ByComponent: counts(func(e daqlog.Error) string { return e.Component }),
+		ByHost:      counts(func(e daqlog.Error) string { return e.Host }),
+		ByErrorType: counts(func(e daqlog.Error) string { return e.ErrorType }),
+	}
+	var first, last time.Time
+	for _, e := range filtered {
+		t, ok := daqlog.ErrorTime(e)
+		if !ok {
+			resp.Untimed++
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first, resp.First = t, daqlog.ErrorClock(e, "15:04:05")
+		}
+		if last.IsZero() || t.After(last) {
+			last, resp.Last = t, daqlog.ErrorClock(e, "15:04:05")
+		}
+	}
+	writeJSON(w, r, resp)
+}
+
+// writeJSON writes v as JSON with a content-hash ETag, answering 304 when the
+// client already has the same representation
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+// etagMatches checks an If-None-Match header value against an ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAPIError writes a JSON error body with the given status
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// intParam parses an optional integer query parameter
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
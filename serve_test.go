@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// testAPI serves two hutches from memory: five tmo errors on 2025-11-19 (two
+// critical, three from drp1), one on 2025-11-18, and two rix errors, the
+// second without a usable time
+func testAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	var errors []daqlog.Error
+	add := func(hutch, date, ts, component, level, msg string) {
+		errors = append(errors, daqlog.Error{
+			ID:        len(errors) + 1,
+			Hutch:     hutch,
+			DateRef:   date,
+			Timestamp: ts,
+			Component: component,
+			Host:      "drp-srcf-cmp001",
+			LogLevel:  level,
+			ErrorType: "timeout",
+			Message:   msg,
+			FilePath:  fmt.Sprintf("/logs/%s/%s.log", hutch, component),
+		})
+	}
+	add("tmo", "2025-11-19", "2025-11-19 16:00:00", "drp1", "E", "Timeout waiting for PV TMO:DAQ:1")
+	add("tmo", "2025-11-19", "2025-11-19 16:00:01", "drp1", "C", "DMA buffer 3 overflow")
+	add("tmo", "2025-11-19", "2025-11-19 16:00:02", "teb0", "E", "Timeout waiting for transition")
+	add("tmo", "2025-11-19", "2025-11-19 16:00:03", "drp1", "E", "Timeout waiting for PV TMO:DAQ:2")
+	add("tmo", "2025-11-19", "2025-11-19 16:00:04", "meb0", "C", "Out of memory")
+	add("tmo", "2025-11-18", "2025-11-18 16:00:00", "drp1", "E", "Timeout waiting for PV TMO:DAQ:1")
+	add("rix", "2025-11-19", "2025-11-19 17:00:00", "xpm", "E", "PGP lane 2 link down")
+	add("rix", "2025-11-19", "", "xpm", "E", "PGP lane 3 link down")
+	errors[0].ContextBefore = "line before"
+
+	srv := httptest.NewServer(newAPIHandler(daqlog.NewMemoryStore(errors)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getJSON fetches path and decodes a 200 response into v
+func getJSON(t *testing.T, srv *httptest.Server, path string, v any) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d, want 200", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decoding: %v", path, err)
+	}
+}
+
+func TestAPIHutchesAndDates(t *testing.T) {
+	srv := testAPI(t)
+
+	var hutches []struct {
+		Hutch  string `json:"hutch"`
+		Files  int    `json:"files"`
+		Errors int    `json:"errors"`
+	}
+	getJSON(t, srv, "/api/hutches", &hutches)
+	if len(hutches) != 2 || hutches[0].Hutch != "rix" || hutches[1].Hutch != "tmo" {
+		t.Fatalf("hutches = %+v, want rix then tmo", hutches)
+	}
+	if hutches[1].Errors != 6 || hutches[1].Files != 3 {
+		t.Errorf("tmo = %+v, want 6 errors in 3 files", hutches[1])
+	}
+
+	var dates []struct {
+		Date   string `json:"date"`
+		Errors int    `json:"errors"`
+	}
+	getJSON(t, srv, "/api/hutches/tmo/dates", &dates)
+	if len(dates) != 2 || dates[0].Date != "2025-11-19" || dates[0].Errors != 5 || dates[1].Date != "2025-11-18" {
+		t.Errorf("dates = %+v, want 2025-11-19 (5) then 2025-11-18", dates)
+	}
+}
+
+// errorsPage is the /api/errors response
+type errorsPage struct {
+	Total      int  `json:"total"`
+	Offset     int  `json:"offset"`
+	Limit      int  `json:"limit"`
+	NextOffset *int `json:"next_offset"`
+	Errors     []struct {
+		ID            int    `json:"id"`
+		Component     string `json:"component"`
+		Level         string `json:"level"`
+		TimePacific   string `json:"time_pacific"`
+		ContextBefore string `json:"context_before"`
+	} `json:"errors"`
+}
+
+func TestAPIErrorsPaging(t *testing.T) {
+	srv := testAPI(t)
+
+	var ids []int
+	for offset := 0; ; {
+		var page errorsPage
+		getJSON(t, srv, fmt.Sprintf("/api/errors?hutch=tmo&date=2025-11-19&limit=2&offset=%d", offset), &page)
+		if page.Total != 5 || page.Limit != 2 || page.Offset != offset {
+			t.Fatalf("page at %d = total %d limit %d offset %d", offset, page.Total, page.Limit, page.Offset)
+		}
+		for _, e := range page.Errors {
+			ids = append(ids, e.ID)
+		}
+		if page.NextOffset == nil {
+			break
+		}
+		offset = *page.NextOffset
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Errorf("paged IDs = %v, want [1 2 3 4 5]", ids)
+	}
+
+	var page errorsPage
+	getJSON(t, srv, "/api/errors?hutch=tmo&date=2025-11-19&limit=1&context=1", &page)
+	if page.Errors[0].ContextBefore != "line before" || page.Errors[0].TimePacific != "08:00:00" {
+		t.Errorf("first error = %+v, want context and Pacific time 08:00:00", page.Errors[0])
+	}
+}
+
+func TestAPIErrorsFilters(t *testing.T) {
+	srv := testAPI(t)
+
+	for _, tt := range []struct {
+		query string
+		want  string // IDs
+	}{
+		{"level=c", "[2 5]"},
+		{"component=DRP", "[1 2 4]"},
+		{"message=pv+tmo", "[1 4]"},
+		{"component=drp&level=C", "[2]"},
+		{"component=nothing", "[]"},
+	} {
+		var page errorsPage
+		getJSON(t, srv, "/api/errors?hutch=tmo&date=2025-11-19&"+tt.query, &page)
+		var ids []int
+		for _, e := range page.Errors {
+			ids = append(ids, e.ID)
+		}
+		if got := fmt.Sprint(ids); got != tt.want || page.Total != len(ids) {
+			t.Errorf("%s: IDs %s (total %d), want %s", tt.query, got, page.Total, tt.want)
+		}
+	}
+}
+
+func TestAPIBadRequests(t *testing.T) {
+	srv := testAPI(t)
+
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/api/errors/999", http.StatusNotFound},
+		{"/api/errors/abc", http.StatusBadRequest},
+		{"/api/errors?hutch=tmo", http.StatusBadRequest},
+		{"/api/errors?hutch=tmo&date=19-11-2025", http.StatusBadRequest},
+		{"/api/errors?hutch=tmo&date=2025-11-19&limit=0", http.StatusBadRequest},
+		{"/api/errors?hutch=tmo&date=2025-11-19&offset=-1", http.StatusBadRequest},
+	} {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || body.Error == "" {
+			t.Errorf("GET %s: status %d, error %q; want %d with an error message", tt.path, resp.StatusCode, body.Error, tt.status)
+		}
+	}
+
+	var e struct {
+		ID    int    `json:"id"`
+		Hutch string `json:"hutch"`
+	}
+	getJSON(t, srv, "/api/errors/7", &e)
+	if e.ID != 7 || e.Hutch != "rix" {
+		t.Errorf("error 7 = %+v, want the rix error", e)
+	}
+}
+
+func TestAPIETag(t *testing.T) {
+	srv := testAPI(t)
+	path := srv.URL + "/api/stats?hutch=tmo&date=2025-11-19"
+
+	resp, err := http.Get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q; want 200 with an ETag", resp.StatusCode, etag)
+	}
+
+	for _, tt := range []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{`"other", W/` + etag, http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("If-None-Match %s: status %d, want %d", tt.ifNoneMatch, resp.StatusCode, tt.status)
+		}
+		if got := resp.Header.Get("ETag"); got != etag {
+			t.Errorf("If-None-Match %s: ETag %q, want %q", tt.ifNoneMatch, got, etag)
+		}
+	}
+}
+
+func TestAPIStatsTimes(t *testing.T) {
+	srv := testAPI(t)
+
+	for _, tt := range []struct {
+		query       string
+		first, last string
+		untimed     int
+	}{
+		{"hutch=tmo&date=2025-11-19", "08:00:00", "08:00:04", 0},
+		// The untimed error sorts last but does not hide the timed one
+		{"hutch=rix&date=2025-11-19", "09:00:00", "09:00:00", 1},
+		{"hutch=rix&date=2025-11-19&message=lane+3", "", "", 1},
+	} {
+		var stats struct {
+			Total   int    `json:"total"`
+			First   string `json:"first"`
+			Last    string `json:"last"`
+			Untimed int    `json:"untimed"`
+		}
+		getJSON(t, srv, "/api/stats?"+tt.query, &stats)
+		if stats.First != tt.first || stats.Last != tt.last || stats.Untimed != tt.untimed {
+			t.Errorf("%s: first %q, last %q, untimed %d; want %q, %q, %d",
+				tt.query, stats.First, stats.Last, stats.Untimed, tt.first, tt.last, tt.untimed)
+		}
+	}
+}
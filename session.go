@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// session is the browser state saved on quit and offered on the next start
+type session struct {
+	DB     []string  `json:"db"` // Databases browsed, main first
+	View   viewState `json:"view"`
+	Zoomed bool      `json:"zoomed,omitempty"`
+	Saved  time.Time `json:"saved"`
+}
+
+// sessionPath returns the state file, under $XDG_STATE_HOME or ~/.local/state
+func sessionPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "lcls-daq-browser", "session.json"), nil
+}
+
+// loadSession reads the saved session, returning nil if there is none
+func loadSession(path string) (*session, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// saveSession writes the session through a temporary file, so that a crash
+// never leaves a half-written state file
+func saveSession(path string, s *session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// session returns the state to save on quit: the last date picker or error
+// list visited, on the active side of a split. It is nil when nothing past
+// the hutch picker was opened, so the previous session is kept.
+func (m Model) session() *session {
+	if m.split != nil {
+		m = m.split.sides[m.split.active]
+	}
+	h := m.history
+	if h == nil {
+		return nil
+	}
+	for i := min(h.index, len(h.entries)-1); i >= 0; i-- {
+		v := h.entries[i]
+		if v.Mode == ModeHutchPicker {
+			continue
+		}
+		var files []string
+		if s, ok := m.store.(interface{ Files() []string }); ok {
+			files = s.Files()
+		}
+		return &session{DB: files, View: v, Zoomed: m.zoomed, Saved: time.Now()}
+	}
+	return nil
+}
+
+// restoreSession reopens a saved session. If its error no longer exists the
+// error list opens on the same group row, with a note saying so.
+func (m *Model) restoreSession(s session) tea.Cmd {
+	v := s.View
+	return m.openView(v, func(m *Model) {
+		m.zoomed = s.Zoomed
+		if v.Mode == ModeDatePicker {
+			m.applyGrouping(v) // For the date opened next
+		}
+		if v.Mode != ModeErrorList || v.ErrorID <= 0 {
+			return
+		}
+		if e := m.selectedError(); e == nil || e.ID != v.ErrorID {
+			m.loadNote = fmt.Sprintf("Error %d from the last session no longer exists", v.ErrorID)
+		}
+	})
+}
+
+// updateSessionOffer answers the restore prompt shown at startup: enter
+// restores, esc dismisses and any other key dismisses it and goes on as usual.
+// handled is false for those other keys.
+func (m Model) updateSessionOffer(msg tea.KeyMsg) (model Model, cmd tea.Cmd, handled bool) {
+	s := *m.sessionOffer
+	m.sessionOffer = nil
+	switch {
+	case key.Matches(msg, m.keys.Enter):
+		return m, m.restoreSession(s), true
+	case key.Matches(msg, m.keys.Back):
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// viewSessionOffer renders the restore prompt for the hutch picker
+func (m Model) viewSessionOffer() string {
+	s := m.sessionOffer
+	saved := s.Saved.In(m.displayLoc).Format("Jan 2 15:04")
+	return fmt.Sprintf("Restore last session? %s\n%s\n\n",
+		selectedStyle.Render(truncate(s.View.Label(), max(m.width-24, 20))),
+		helpStyle.Render("saved "+saved+" · enter restore · esc dismiss"))
+}
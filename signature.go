@@ -0,0 +1,32 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns replaced when normalizing a message into a signature, applied in order
+var signaturePatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), `"<str>"`},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?Z?\b`), "<ts>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// messageSignature normalizes an error message so that messages differing only
+// in numbers, addresses, timestamps or quoted values share a signature.
+// "Timeout on PV TMO:DAQ:12 from 172.21.3.9" -> "Timeout on PV TMO:DAQ:<n> from <ip>"
+func messageSignature(msg string) string {
+	sig := msg
+	for _, p := range signaturePatterns {
+		sig = p.re.ReplaceAllString(sig, p.repl)
+	}
+	return strings.TrimSpace(sig)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// GroupSort selects the order of the groups panel
+type GroupSort int
+
+const (
+	SortGroupsByTime      GroupSort = iota // First occurrence (default)
+	SortGroupsByCount                      // Most errors first
+	SortGroupsByCriticals                  // Most criticals first
+	SortGroupsByName                       // Grouping key, alphabetically
+	numGroupSorts
+)
+
+// String returns the short name shown in the groups pane header
+func (s GroupSort) String() string {
+	switch s {
+	case SortGroupsByCount:
+		return "count"
+	case SortGroupsByCriticals:
+		return "crit"
+	case SortGroupsByName:
+		return "name"
+	default:
+		return "time"
+	}
+}
+
+// ErrorSort selects the order of errors within a group
+type ErrorSort int
+
+const (
+	SortErrorsByTime  ErrorSort = iota // Chronological (default)
+	SortErrorsByLine                   // File, then line number
+	SortErrorsByLevel                  // Criticals first, then chronological
+	numErrorSorts
+)
+
+// String returns the short name shown in the errors pane header
+func (s ErrorSort) String() string {
+	switch s {
+	case SortErrorsByLine:
+		return "line"
+	case SortErrorsByLevel:
+		return "level"
+	default:
+		return "time"
+	}
+}
+
+// sortGroups orders m.groups and the errors inside each group.
+// Groups arrive in time order from buildGroups, so stable sorts keep time as the
+// tie-break; errors arrive in chronological order from Store.Errors.
+func (m *Model) sortGroups() {
+	switch m.groupSort {
+	case SortGroupsByCount:
+		sort.SliceStable(m.groups, func(i, j int) bool {
+			return len(m.groups[i].Errors) > len(m.groups[j].Errors)
+		})
+	case SortGroupsByCriticals:
+		sort.SliceStable(m.groups, func(i, j int) bool {
+			ci, cj := countCriticals(m.groups[i].Errors), countCriticals(m.groups[j].Errors)
+			if ci != cj {
+				return ci > cj
+			}
+			return len(m.groups[i].Errors) > len(m.groups[j].Errors)
+		})
+	case SortGroupsByName:
+		sort.SliceStable(m.groups, func(i, j int) bool {
+			return m.groups[i].Key < m.groups[j].Key
+		})
+	}
+
+	for gi := range m.groups {
+		errors := m.groups[gi].Errors
+		switch m.errorSort {
+		case SortErrorsByLine:
+			sort.SliceStable(errors, func(i, j int) bool {
+				if errors[i].FilePath != errors[j].FilePath {
+					return errors[i].FilePath < errors[j].FilePath
+				}
+				return errors[i].LineNumber < errors[j].LineNumber
+			})
+		case SortErrorsByLevel:
+			sort.SliceStable(errors, func(i, j int) bool {
+				return levelRank(errors[i]) < levelRank(errors[j])
+			})
+		}
+	}
+}
+
+// levelRank orders log levels for sorting: critical, error, then anything else
+func levelRank(e daqlog.Error) int {
+	switch e.LogLevel {
+	case "C":
+		return 0
+	case "E":
+		return 1
+	}
+	return 2
+}
+
+// countCriticals returns the number of critical errors
+func countCriticals(errors []daqlog.Error) int {
+	n := 0
+	for _, e := range errors {
+		if e.LogLevel == "C" {
+			n++
+		}
+	}
+	return n
+}
+
+// cycleSort advances the sort mode of the focused panel, keeping the selection
+func (m *Model) cycleSort() {
+	switch m.focusedPanel {
+	case PanelGroups:
+		m.groupSort = (m.groupSort + 1) % numGroupSorts
+	case PanelErrors:
+		m.errorSort = (m.errorSort + 1) % numErrorSorts
+	default:
+		return
+	}
+
+	var currentErrorID int
+	if e := m.selectedError(); e != nil {
+		currentErrorID = e.ID
+	}
+	m.buildGroups()
+	m.groupCursor = 0
+	m.errorCursor = 0
+	m.groupOffset = 0
+	m.errorOffset = 0
+	if currentErrorID > 0 {
+		m.findAndSelectError(currentErrorID)
+	}
+	m.updateContextPane()
+}
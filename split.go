@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// splitState holds the two independent sides of the split-screen comparison.
+// Each side is a complete Model with its own hutch, date and filters.
+type splitState struct {
+	sides    [2]Model
+	active   int  // Side receiving keys (0 = left, 1 = right)
+	timeLock bool // Moving one side moves the other to the nearest clock time
+}
+
+// openSplit starts a split with both sides showing the current view
+func (m *Model) openSplit() {
+	m.cancelLoad()
+	side := *m
+	side.split = nil
+	side.inSplit = true
+	side.zoomed = false
+
+	m.split = &splitState{sides: [2]Model{side, side}}
+	m.split.sides[0].splitActive = true
+	m.split.sides[1].spinner = newSpinner() // Its own ID, so ticks reach one side each
+	m.split.sides[1].history = m.history.clone()
+	m.resizeSplit()
+}
+
+// closeSplit returns to a single view of the active side
+func (m *Model) closeSplit() {
+	side := m.split.sides[m.split.active]
+	side.inSplit = false
+	side.splitActive = false
+	side.width = m.width
+	side.height = m.height
+	side.viewport.Width = m.width/3 - 4
+	side.viewport.Height = m.height - 8
+	side.updateContextPane()
+	*m = side
+}
+
+// resizeSplit gives each side half the width, leaving a line for the split footer
+func (m *Model) resizeSplit() {
+	for i := range m.split.sides {
+		s := &m.split.sides[i]
+		s.width = (m.width - 1) / 2
+		s.height = m.height - 1
+		s.viewport.Width = s.width/2 - 4
+		s.viewport.Height = s.height - 8
+		s.updateContextPane()
+	}
+}
+
+// updateSplit routes messages to the sides of a split
+func (m Model) updateSplit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	sp := m.split
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resizeSplit()
+		return m, nil
+
+	case tea.KeyMsg:
+		active := &sp.sides[sp.active]
+		if active.inputMode == InputNone {
+			switch {
+			case key.Matches(msg, m.keys.Split):
+				m.closeSplit()
+				return m, nil
+			case key.Matches(msg, m.keys.SwitchSide):
+				m.setActiveSide(1 - sp.active)
+				return m, nil
+			case key.Matches(msg, m.keys.TimeLock):
+				sp.timeLock = !sp.timeLock
+				if sp.timeLock {
+					m.syncSplitTime()
+				}
+				return m, nil
+			}
+		}
+		return m.forwardToSide(sp.active, msg)
+
+	case tea.MouseMsg:
+		half := (m.width - 1) / 2
+		side := 0
+		if msg.X > half {
+			side = 1
+			msg.X -= half + 1
+		}
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+			m.setActiveSide(side)
+		}
+		return m.forwardToSide(side, msg)
+	}
+
+	// Anything else (cursor blinks, finished loads etc.) goes to both sides
+	var cmds []tea.Cmd
+	for i := range sp.sides {
+		updated, cmd := sp.sides[i].Update(msg)
+		sp.sides[i] = updated.(Model)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// forwardToSide passes a message to one side and applies the time lock afterwards
+func (m Model) forwardToSide(i int, msg tea.Msg) (tea.Model, tea.Cmd) {
+	sp := m.split
+	before := sp.sides[i].selectedClock()
+
+	updated, cmd := sp.sides[i].Update(msg)
+	sp.sides[i] = updated.(Model)
+	if sp.sides[i].quitting {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if sp.timeLock && i == sp.active && sp.sides[i].selectedClock() != before {
+		m.syncSplitTime()
+	}
+	return m, cmd
+}
+
+// setActiveSide moves key focus to a side
+func (m *Model) setActiveSide(i int) {
+	m.split.active = i
+	m.split.sides[0].splitActive = i == 0
+	m.split.sides[1].splitActive = i == 1
+}
+
+// syncSplitTime moves the inactive side to the error nearest the active side's clock time
+func (m *Model) syncSplitTime() {
+	sp := m.split
+	clock := sp.sides[sp.active].selectedClock()
+	if clock < 0 {
+		return
+	}
+	sp.sides[1-sp.active].selectNearestClock(clock)
+}
+
+// selectedClock returns the selected error's display time of day in seconds, or -1.
+// Clock time (not absolute time) lets today be lined up against yesterday.
+func (m Model) selectedClock() int {
+	if m.mode != ModeErrorList {
+		return -1
+	}
+	e := m.selectedError()
+	if e == nil {
+		return -1
+	}
+	t, ok := daqlog.ErrorTime(*e)
+	if !ok {
+		return -1
+	}
+	local := t.In(m.displayLoc)
+	return local.Hour()*3600 + local.Minute()*60 + local.Second()
+}
+
+// selectNearestClock selects the error whose display time of day is closest to clock
+func (m *Model) selectNearestClock(clock int) {
+	if m.mode != ModeErrorList {
+		return
+	}
+
+	bestID := 0
+	bestDiff := math.MaxInt32
+	for _, g := range m.groups {
+		for _, e := range g.Errors {
+			t, ok := daqlog.ErrorTime(e)
+			if !ok {
+				continue
+			}
+			local := t.In(m.displayLoc)
+			diff := abs(local.Hour()*3600 + local.Minute()*60 + local.Second() - clock)
+			if diff < bestDiff {
+				bestDiff = diff
+				bestID = e.ID
+			}
+		}
+	}
+	if bestID == 0 {
+		return
+	}
+
+	m.findAndSelectError(bestID)
+	m.updateContextPane()
+}
+
+func (m Model) viewSplit() string {
+	sp := m.split
+	left := sp.sides[0].View()
+	right := sp.sides[1].View()
+
+	divider := strings.TrimSuffix(strings.Repeat("│\n", m.height-1), "\n")
+	content := lipgloss.JoinHorizontal(lipgloss.Top, left, dividerStyle.Render(divider), right)
+
+	lock := "off"
+	if sp.timeLock {
+		lock = filterStyle.Render("on")
+	}
+	side := "left"
+	if sp.active == 1 {
+		side = "right"
+	}
+	footer := helpStyle.Render(fmt.Sprintf("[split: %s active]  ctrl+w switch side  L time lock (", side)) +
+		lock + helpStyle.Render(")  | close split")
+
+	return content + "\n" + footer
+}
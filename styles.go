@@ -10,6 +10,8 @@ var (
 	colorBlue    = lipgloss.Color("#6699ff")
 	colorGray    = lipgloss.Color("#888888")
 	colorDimGray = lipgloss.Color("#555555")
+	colorCyan    = lipgloss.Color("#66cccc")
+	colorPurple  = lipgloss.Color("#cc99ff")
 
 	// Title bar
 	titleStyle = lipgloss.NewStyle().
@@ -60,6 +62,33 @@ var (
 	lineNumberStyle = lipgloss.NewStyle().
 			Foreground(colorDimGray)
 
+	// Tokens in context lines
+	timeTokenStyle = lipgloss.NewStyle().
+			Foreground(colorBlue)
+
+	hostTokenStyle = lipgloss.NewStyle().
+			Foreground(colorGreen)
+
+	pvTokenStyle = lipgloss.NewStyle().
+			Foreground(colorCyan).
+			Bold(true)
+
+	numberTokenStyle = lipgloss.NewStyle().
+				Foreground(colorPurple)
+
+	quotedTokenStyle = lipgloss.NewStyle().
+				Foreground(colorYellow)
+
+	// Context search hits
+	searchMatchStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#000000")).
+				Background(colorYellow)
+
+	searchCurrentStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#000000")).
+				Background(colorGreen).
+				Bold(true)
+
 	// Help bar
 	helpStyle = lipgloss.NewStyle().
 			Foreground(colorGray)
@@ -73,10 +102,20 @@ var (
 			Foreground(colorGray).
 			Padding(0, 1)
 
+	// Split-screen divider
+	dividerStyle = lipgloss.NewStyle().
+			Foreground(colorDimGray)
+
 	// Filter indicator
 	filterStyle = lipgloss.NewStyle().
 			Foreground(colorYellow).
 			Bold(true)
+
+	// Text matched by the component or message filter
+	filterMatchStyle = lipgloss.NewStyle().
+				Foreground(colorYellow).
+				Bold(true).
+				Underline(true)
 )
 
 // ErrorLevelStyle returns style based on log level
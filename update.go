@@ -2,13 +2,37 @@ package main
 
 import (
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// In split-screen mode the sides handle everything
+	if m.split != nil {
+		return m.updateSplit(msg)
+	}
+
+	updated, cmd := m.update(msg)
+
+	// Fetch context for whatever is selected now, unless a split just opened
+	next := updated.(Model)
+	if next.split == nil {
+		next.syncHistory()
+		if fetch := next.fetchContext(); fetch != nil {
+			cmd = tea.Batch(cmd, fetch)
+		}
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case contextMsg:
+		m.handleContext(msg)
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -29,22 +53,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case tea.KeyMsg:
-		// If in input mode, handle text input first
-		if m.inputMode != InputNone {
-			return m.updateInput(msg)
+	case loadedMsg:
+		cmd := m.handleLoaded(msg)
+		return m, cmd
+
+	case spinner.TickMsg:
+		if m.loading == nil {
+			return m, nil // Let the spinner stop between loads
 		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		m.loadNote = ""
 
-		// Handle based on mode
-		switch m.mode {
-		case ModeHutchPicker:
-			return m.updateHutchPicker(msg)
-		case ModeDatePicker:
-			return m.updateDatePicker(msg)
-		case ModeErrorList:
-			return m.updateErrorList(msg)
+		// Esc cancels a slow load before it means "back"
+		if m.loading != nil && m.inputMode == InputNone && key.Matches(msg, m.keys.Back) {
+			m.loadNote = "Cancelled: " + m.loading.label
+			m.cancelLoad()
+			return m, nil
 		}
 
+		updated, cmd := m.updateKey(msg)
+		next := updated.(Model)
+		next.dropStaleLoad()
+		return next, cmd
+
 	case tea.MouseMsg:
 		// Ignore mouse in input mode
 		if m.inputMode != InputNone {
@@ -59,6 +94,54 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateKey routes a key to the text input or the current view
+func (m Model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// If in input mode, handle text input first
+	if m.inputMode == InputContextSearch {
+		return m.updateSearchInput(msg)
+	}
+	if m.inputMode != InputNone {
+		return m.updateInput(msg)
+	}
+
+	if m.sessionOffer != nil {
+		next, cmd, ok := m.updateSessionOffer(msg)
+		if ok {
+			return next, cmd
+		}
+		m = next
+	}
+
+	// History and copying links work alike in every browsing view
+	if m.showHistory {
+		return m.updateHistoryList(msg)
+	}
+	switch m.mode {
+	case ModeHutchPicker, ModeDatePicker, ModeErrorList:
+		if next, cmd, ok := m.updateHistoryKey(msg); ok {
+			return next, cmd
+		}
+		if next, cmd, ok := m.updateCopyKey(msg); ok {
+			return next, cmd
+		}
+	}
+
+	// Handle based on mode
+	switch m.mode {
+	case ModeHutchPicker:
+		return m.updateHutchPicker(msg)
+	case ModeDatePicker:
+		return m.updateDatePicker(msg)
+	case ModeErrorList:
+		return m.updateErrorList(msg)
+	case ModeCorrelation:
+		return m.updateCorrelation(msg)
+	case ModeDiff:
+		return m.updateDiff(msg)
+	}
+	return m, nil
+}
+
 func (m Model) updateHutchPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, m.keys.Quit):
@@ -82,16 +165,12 @@ func (m Model) updateHutchPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.hutchCursor = len(m.hutches) - 1
 
 	case key.Matches(msg, m.keys.Enter):
+		if len(m.hutches) == 0 && m.loading == nil {
+			// The hutch list never loaded (cancelled): try again
+			return m, m.loadHutches("", "", nil)
+		}
 		if m.hutchCursor < len(m.hutches) {
-			m.selectedHutch = m.hutches[m.hutchCursor].Hutch
-			dates, err := GetDatesWithErrors(m.db, m.selectedHutch)
-			if err != nil {
-				m.err = err
-				return m, nil
-			}
-			m.dates = dates
-			m.cursor = 0
-			m.mode = ModeDatePicker
+			return m, m.loadDates(m.hutches[m.hutchCursor].Hutch, "", nil)
 		}
 
 	case key.Matches(msg, m.keys.Help):
@@ -108,9 +187,7 @@ func (m Model) updateDatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case key.Matches(msg, m.keys.Back):
-		// Go back to hutch picker
-		m.mode = ModeHutchPicker
-		m.dates = nil
+		m.backToHutchPicker()
 
 	case key.Matches(msg, m.keys.Up):
 		if m.cursor > 0 {
@@ -130,24 +207,12 @@ func (m Model) updateDatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case key.Matches(msg, m.keys.Enter):
 		if m.cursor < len(m.dates) {
-			m.selectedDate = m.dates[m.cursor].Date
-			errors, err := LoadErrors(m.db, m.selectedHutch, m.selectedDate)
-			if err != nil {
-				m.err = err
-				return m, nil
-			}
-			m.allErrors = errors
-			m.filteredErrors = errors
-			m.levelFilter = ""
-			m.componentFilter = ""
-			m.buildGroups()
-			m.mode = ModeErrorList
-			m.focusedPanel = PanelGroups
-			m.groupCursor = 0
-			m.errorCursor = 0
-			m.groupOffset = 0
-			m.errorOffset = 0
-			m.updateContextPane()
+			return m, m.loadErrors(m.dates[m.cursor].Date, nil)
+		}
+
+	case key.Matches(msg, m.keys.Diff):
+		if m.cursor < len(m.dates) {
+			return m, m.startDiffInput(m.dates[m.cursor].Date)
 		}
 
 	case key.Matches(msg, m.keys.Help):
@@ -172,18 +237,12 @@ func (m Model) updateErrorList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Go back to groups panel
 			m.focusedPanel = PanelGroups
 		default:
-			// Go back to date picker
-			m.mode = ModeDatePicker
-			m.cursor = 0
-			for i, d := range m.dates {
-				if d.Date == m.selectedDate {
-					m.cursor = i
-					break
-				}
+			// Drilled in from a diff: go back to the diff list
+			if m.diffDrillLabel != "" {
+				m.leaveDiffDrill()
+				return m, nil
 			}
-			m.allErrors = nil
-			m.filteredErrors = nil
-			m.groups = nil
+			m.backToDatePicker()
 		}
 
 	case key.Matches(msg, m.keys.Tab):
@@ -284,10 +343,16 @@ func (m Model) updateErrorList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.filterInput.Focus()
 			return m, textinput.Blink
 		case PanelContext:
-			// No-op for context panel
-			return m, nil
+			return m.startContextSearch()
 		}
 
+	// Cycle through the context search's matches
+	case m.search.query != "" && key.Matches(msg, m.keys.NextMatch):
+		m.stepMatch(1)
+
+	case m.search.query != "" && key.Matches(msg, m.keys.PrevMatch):
+		m.stepMatch(-1)
+
 	// Clear all filters
 	case key.Matches(msg, m.keys.ClearFilter):
 		m.clearFilters()
@@ -295,11 +360,90 @@ func (m Model) updateErrorList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Toggle zoom mode
 	case key.Matches(msg, m.keys.Zoom):
 		m.zoomed = !m.zoomed
+
+	// Correlation window around the selected error
+	case key.Matches(msg, m.keys.Correlate):
+		return m, m.openCorrelation()
+
+	// Jump between burst root-cause candidates
+	case key.Matches(msg, m.keys.NextRootCause):
+		m.jumpToRootCause(1)
+
+	case key.Matches(msg, m.keys.PrevRootCause):
+		m.jumpToRootCause(-1)
+
+	// Toggle incidents in place of time/component groups
+	case key.Matches(msg, m.keys.Incidents):
+		m.showIncidents = !m.showIncidents
+		m.regroupKeepSelection()
+
+	// Cycle grouping key and time bucket
+	case key.Matches(msg, m.keys.GroupKey):
+		m.cycleGroupKey()
+
+	case key.Matches(msg, m.keys.TimeBucket):
+		m.cycleTimeBucket()
+
+	// Cycle sort order of the focused panel
+	case key.Matches(msg, m.keys.Sort):
+		m.cycleSort()
+
+	// Split screen with a second independent view
+	case key.Matches(msg, m.keys.Split):
+		if !m.inSplit {
+			m.openSplit()
+		}
+
+	// Compare signatures with another date or range
+	case key.Matches(msg, m.keys.Diff):
+		if m.diffDrillLabel == "" {
+			return m, m.startDiffInput(m.selectedDate)
+		}
+
+	case key.Matches(msg, m.keys.Zone):
+		m.cycleZone()
+
+	// Adjust the incident quiet gap
+	case key.Matches(msg, m.keys.WindowWider):
+		if m.showIncidents {
+			m.setIncidentGap(m.currentIncidentGap() * 2)
+		}
+
+	case key.Matches(msg, m.keys.WindowNarrower):
+		if m.showIncidents {
+			m.setIncidentGap(m.currentIncidentGap() / 2)
+		}
 	}
 
 	return m, nil
 }
 
+// backToDatePicker leaves the error list for the date picker, on the date
+// that was open
+func (m *Model) backToDatePicker() {
+	m.mode = ModeDatePicker
+	m.cursor = 0
+	for i, d := range m.dates {
+		if d.Date == m.selectedDate {
+			m.cursor = i
+			break
+		}
+	}
+	m.allErrors = nil
+	m.filteredErrors = nil
+	m.groups = nil
+}
+
+// backToHutchPicker returns to the hutch picker from the date picker or
+// error list
+func (m *Model) backToHutchPicker() {
+	if m.mode == ModeErrorList {
+		m.backToDatePicker()
+	}
+	m.mode = ModeHutchPicker
+	m.dates = nil
+}
+
 // Navigation helpers for three-panel layout
 
 func (m *Model) navigateUp() {
@@ -475,10 +619,12 @@ func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.inputMode = InputNone
 		m.timeInput.Blur()
 		m.filterInput.Blur()
+		m.diffInput.Blur()
 		return m, nil
 
 	case tea.KeyEnter:
 		// Apply input
+		var cmd tea.Cmd
 		switch m.inputMode {
 		case InputTimeJump:
 			timeStr := m.timeInput.Value()
@@ -489,11 +635,18 @@ func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case InputMessageFilter:
 			m.messageFilter = m.filterInput.Value()
 			m.applyMessageFilter()
+		case InputDiff:
+			cmd = m.openDiff(m.diffInput.Value(), m.diffDefaultTarget)
+			if m.diffErr != "" {
+				// Keep the dialog open so the range can be corrected
+				return m, nil
+			}
 		}
 		m.inputMode = InputNone
 		m.timeInput.Blur()
 		m.filterInput.Blur()
-		return m, nil
+		m.diffInput.Blur()
+		return m, cmd
 	}
 
 	// Update the active text input
@@ -503,6 +656,8 @@ func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.timeInput, cmd = m.timeInput.Update(msg)
 	case InputComponentFilter, InputMessageFilter:
 		m.filterInput, cmd = m.filterInput.Update(msg)
+	case InputDiff:
+		m.diffInput, cmd = m.diffInput.Update(msg)
 	}
 	return m, cmd
 }
@@ -588,6 +743,9 @@ func (m Model) handleMouseDatePicker(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleMouseErrorList(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	// Calculate panel boundaries
 	panelWidth := (m.width - 6) / 3
+	if m.inSplit {
+		panelWidth = (m.width - 4) / 2
+	}
 	listStartY := 3 // Content starts at row 3 (after header + separator)
 	visibleRows := m.height - 10
 	if visibleRows < 1 {
@@ -598,7 +756,7 @@ func (m Model) handleMouseErrorList(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	var clickedPanel Panel
 	if msg.X < panelWidth+2 {
 		clickedPanel = PanelGroups
-	} else if msg.X < panelWidth*2+4 {
+	} else if msg.X < panelWidth*2+4 || m.inSplit {
 		clickedPanel = PanelErrors
 	} else {
 		clickedPanel = PanelContext
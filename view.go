@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func (m Model) View() string {
@@ -20,6 +21,14 @@ func (m Model) View() string {
 		return "Loading..."
 	}
 
+	if m.split != nil {
+		return m.viewSplit()
+	}
+
+	if m.showHistory {
+		return m.viewHistory()
+	}
+
 	var view string
 	switch m.mode {
 	case ModeHutchPicker:
@@ -28,6 +37,10 @@ func (m Model) View() string {
 		view = m.viewDatePicker()
 	case ModeErrorList:
 		view = m.viewErrorList()
+	case ModeCorrelation:
+		view = m.viewCorrelation()
+	case ModeDiff:
+		view = m.viewDiff()
 	default:
 		view = ""
 	}
@@ -37,6 +50,10 @@ func (m Model) View() string {
 		view = m.overlayInput(view)
 	}
 
+	if line := m.viewLoading(); line != "" {
+		view += "\n" + line
+	}
+
 	return view
 }
 
@@ -48,6 +65,10 @@ func (m Model) viewHutchPicker() string {
 	sb.WriteString(title)
 	sb.WriteString("\n\n")
 
+	if m.sessionOffer != nil {
+		sb.WriteString(m.viewSessionOffer())
+	}
+
 	// Instructions
 	sb.WriteString("Select a hutch to browse:\n\n")
 
@@ -70,6 +91,10 @@ func (m Model) viewHutchPicker() string {
 		sb.WriteString(style.Render(line))
 		sb.WriteString("\n")
 	}
+	if len(m.hutches) == 0 && m.loading == nil {
+		sb.WriteString(helpStyle.Render("No hutches loaded - press enter to retry"))
+		sb.WriteString("\n")
+	}
 
 	// Help
 	sb.WriteString("\n")
@@ -112,6 +137,10 @@ func (m Model) viewDatePicker() string {
 		sb.WriteString(style.Render(line))
 		sb.WriteString("\n")
 	}
+	if len(m.dates) == 0 && m.loading == nil {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("No dates with errors for %s - press esc to go back", strings.ToUpper(m.selectedHutch))))
+		sb.WriteString("\n")
+	}
 
 	// Help
 	sb.WriteString("\n")
@@ -139,23 +168,44 @@ func (m Model) viewErrorList() string {
 	}
 
 	// Build three panels
-	leftPane := m.buildGroupsPane(panelWidth)
-	middlePane := m.buildErrorsPane(panelWidth)
-	rightPane := m.buildContextPane(panelWidth)
-
-	// Join horizontally
-	content := lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		leftPane,
-		" ",
-		middlePane,
-		" ",
-		rightPane,
-	)
+	var content string
+	if m.inSplit {
+		// Split-screen sides show groups and errors only
+		panelWidth = max((m.width-4)/2, 20)
+		content = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			m.buildGroupsPane(panelWidth),
+			" ",
+			m.buildErrorsPane(panelWidth),
+		)
+	} else {
+		leftPane := m.buildGroupsPane(panelWidth)
+		middlePane := m.buildErrorsPane(panelWidth)
+		rightPane := m.buildContextPane(panelWidth)
+
+		// Join horizontally
+		content = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			leftPane,
+			" ",
+			middlePane,
+			" ",
+			rightPane,
+		)
+	}
 
 	// Title bar with filter indicators
-	titleText := fmt.Sprintf("DAQ Errors - %s - %s", strings.ToUpper(m.selectedHutch), m.selectedDate)
+	titleText := fmt.Sprintf("DAQ Errors - %s - %s - %s", strings.ToUpper(m.selectedHutch), m.selectedDate, zoneName(m.displayLoc))
+	if m.diffDrillLabel != "" {
+		titleText = fmt.Sprintf("DAQ Errors - %s - %s", strings.ToUpper(m.selectedHutch), m.diffDrillLabel)
+	}
+	if m.splitActive {
+		titleText = "● " + titleText
+	}
 	title := titleStyle.Render(titleText)
+	if m.inSplit && !m.splitActive {
+		title = statusStyle.Render(titleText)
+	}
 	sb.WriteString(title)
 
 	// Filter indicators in title line
@@ -179,6 +229,9 @@ func (m Model) viewErrorList() string {
 		if m.groupCursor < len(m.groups) {
 			g := m.groups[m.groupCursor]
 			status += fmt.Sprintf("  |  Error %d/%d in group", m.errorCursor+1, len(g.Errors))
+			if g.Incident != nil {
+				status += "  |  " + g.Incident.Summary()
+			}
 		}
 		if len(m.filteredErrors) != len(m.allErrors) {
 			status += fmt.Sprintf("  |  %d of %d total", len(m.filteredErrors), len(m.allErrors))
@@ -193,6 +246,8 @@ func (m Model) viewErrorList() string {
 	if m.showHelp {
 		sb.WriteString("\n")
 		sb.WriteString(m.help.View(m.keys))
+	} else if m.inSplit {
+		sb.WriteString(helpStyle.Render("? help"))
 	} else {
 		focusHint := "groups"
 		switch m.focusedPanel {
@@ -201,7 +256,7 @@ func (m Model) viewErrorList() string {
 		case PanelContext:
 			focusHint = "context"
 		}
-		sb.WriteString(helpStyle.Render(fmt.Sprintf("↑↓ nav [%s]  tab switch  t time  c crit  / filter  a all  z zoom  q quit", focusHint)))
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("↑↓ nav [%s]  tab switch  t time  c crit  / filter  a all  z zoom  x corr  r root  i incidents  v group  T bucket  s sort  Z zone  n/N match  | split  [ ] back/fwd  ' history  y link  q quit", focusHint)))
 	}
 
 	return sb.String()
@@ -212,14 +267,28 @@ func (m Model) buildGroupsPane(width int) string {
 	var sb strings.Builder
 
 	// Header
-	header := "Groups"
+	name := "Groups"
+	if m.showIncidents {
+		name = "Incidents"
+	}
+	header := name
 	if m.focusedPanel == PanelGroups {
-		header = dateHeaderStyle.Render("▸ Groups")
+		header = dateHeaderStyle.Render("▸ " + name)
 	} else {
-		header = normalStyle.Render("  Groups")
+		header = normalStyle.Render("  " + name)
 	}
+	count := fmt.Sprintf(" (%d)", len(m.groups))
 	sb.WriteString(header)
-	sb.WriteString(fmt.Sprintf(" (%d)", len(m.groups)))
+	sb.WriteString(count)
+
+	// Grouping and sort annotation, truncated to fit narrow (split-screen) panels
+	annotation := fmt.Sprintf(" by %s/%s ↓%s", m.groupKey, m.timeBucket, m.groupSort)
+	if m.showIncidents {
+		annotation = fmt.Sprintf(" gap %s ↓%s", formatDuration(m.currentIncidentGap()), m.groupSort)
+	}
+	if room := width - 4 - len(name) - 2 - len(count); room >= 8 {
+		sb.WriteString(helpStyle.Render(truncate(annotation, room)))
+	}
 	sb.WriteString("\n")
 	sb.WriteString(strings.Repeat("─", min(width-2, 25)))
 	sb.WriteString("\n")
@@ -252,12 +321,22 @@ func (m Model) buildGroupsPane(width int) string {
 			cursor = "▸ "
 		}
 
-		// Format: "07:50 teb0 (15)"
-		comp := g.Component
-		if len(comp) > 12 {
-			comp = comp[:9] + "..."
+		// Format: "07:50 teb0 (15)"; the key column widens when there is no time
+		keyWidth := width - 17 - len(g.Time)
+		if keyWidth < 8 {
+			keyWidth = 8
+		}
+		value := g.Key
+		if len(value) > keyWidth {
+			value = value[:keyWidth-3] + "..."
+		}
+		line := fmt.Sprintf("%s %-*s (%d)", g.Time, keyWidth, value, len(g.Errors))
+		if g.Time == "" {
+			line = fmt.Sprintf("%-*s (%d)", keyWidth, value, len(g.Errors))
+		}
+		if g.Incident != nil {
+			line = formatIncidentLine(g, 12)
 		}
-		line := fmt.Sprintf("%s %-12s (%d)", g.Time, comp, len(g.Errors))
 
 		// Style based on selection
 		if i == m.groupCursor {
@@ -265,9 +344,20 @@ func (m Model) buildGroupsPane(width int) string {
 		} else {
 			line = normalStyle.Render(line)
 		}
+		if m.componentFilter != "" && m.groupKey == GroupByComponent && g.Incident == nil {
+			col := 0
+			if g.Time != "" {
+				col = len(g.Time) + 1
+			}
+			line = markFilter(line, col, value, m.componentFilter, i == m.groupCursor)
+		}
 
 		sb.WriteString(cursor)
 		sb.WriteString(line)
+		if badge := rootCauseBadge(m.groupRootCause(g)); badge != "" {
+			sb.WriteString(" ")
+			sb.WriteString(criticalStyle.Render(badge))
+		}
 		sb.WriteString("\n")
 	}
 
@@ -293,11 +383,17 @@ func (m Model) buildErrorsPane(width int) string {
 		g := m.groups[m.groupCursor]
 		sb.WriteString(header)
 		// Show filtered count vs total
+		label := truncate(groupLabel(g), max(width-30, 10))
+		if g.Incident != nil {
+			label = "incident " + g.Incident.Name
+		}
 		if m.messageFilter != "" && len(errors) != len(g.Errors) {
-			sb.WriteString(fmt.Sprintf(" in %s %s (%d/%d)", g.Time, g.Component, len(errors), len(g.Errors)))
+			sb.WriteString(fmt.Sprintf(" in %s (%d/%d)", label, len(errors), len(g.Errors)))
 		} else {
-			sb.WriteString(fmt.Sprintf(" in %s %s (%d)", g.Time, g.Component, len(errors)))
+			sb.WriteString(fmt.Sprintf(" in %s (%d)", label, len(errors)))
 		}
+		sb.WriteString(helpStyle.Render(" ↓" + m.errorSort.String()))
+
 	} else {
 		sb.WriteString(header)
 	}
@@ -352,6 +448,12 @@ func (m Model) buildErrorsPane(width int) string {
 		}
 
 		line := fmt.Sprintf("%s %s", levelStyle.Render(level), msg)
+		if rc, ok := m.rootCauses[e.ID]; ok {
+			line = criticalStyle.Render(rootCauseBadge(rc.Kind)) + " " + line
+		}
+		if m.messageFilter != "" {
+			line = markFilter(line, lipgloss.Width(line)-lipgloss.Width(msg), msg, m.messageFilter, false)
+		}
 
 		sb.WriteString(cursor)
 		sb.WriteString(line)
@@ -367,8 +469,15 @@ func (m Model) buildContextPane(width int) string {
 		return panelBorderStyle(m.focusedPanel == PanelContext).Width(width).Render("No errors")
 	}
 
-	// Use the viewport content with focus-aware border
-	return panelBorderStyle(m.focusedPanel == PanelContext).Width(width).Render(m.viewport.View())
+	// Use the viewport content with focus-aware border, below the search line
+	// when a search is active
+	content := m.viewport.View()
+	if header := m.searchHeader(); header != "" {
+		vp := m.viewport
+		vp.Height--
+		content = ansi.Truncate(header, width-4, "…") + "\n" + vp.View()
+	}
+	return panelBorderStyle(m.focusedPanel == PanelContext).Width(width).Render(content)
 }
 
 // panelBorderStyle returns a style for panel borders based on focus
@@ -399,6 +508,13 @@ func (m Model) overlayInput(baseView string) string {
 	case InputMessageFilter:
 		title = "Filter by Message"
 		prompt = "Message: " + m.filterInput.View()
+	case InputDiff:
+		title = "Diff Signatures"
+		prompt = "Baseline: " + m.diffInput.View() + "\n" +
+			helpStyle.Render("vs "+m.diffDefaultTarget+" unless 'BASE vs TARGET' is given")
+		if m.diffErr != "" {
+			prompt += "\n" + criticalStyle.Render(m.diffErr)
+		}
 	default:
 		return baseView
 	}
@@ -467,6 +583,11 @@ func (m Model) viewZoomedPanel() string {
 	}
 	header := fmt.Sprintf("[ZOOM: %s]  z to exit  ↑↓ nav  tab switch panel", panelName)
 	sb.WriteString(helpStyle.Render(header))
+	if m.focusedPanel == PanelContext {
+		if search := m.searchHeader(); search != "" {
+			sb.WriteString("  " + search)
+		}
+	}
 	sb.WriteString("\n\n")
 
 	// Available height for content
@@ -515,7 +636,13 @@ func (m Model) buildZoomedGroupsPane(width, height int) string {
 		}
 
 		// Format: "> 07:50 component_name (15 errors)"
-		line := fmt.Sprintf("%s%s %-20s (%d errors)", cursor, g.Time, g.Component, len(g.Errors))
+		line := fmt.Sprintf("%s%-20s (%d errors)", cursor, groupLabel(g), len(g.Errors))
+		if m.componentFilter != "" && m.groupKey == GroupByComponent {
+			line = markFilter(line, len(cursor)+len(groupLabel(g))-len(g.Key), g.Key, m.componentFilter, false)
+		}
+		if g.Incident != nil {
+			line = fmt.Sprintf("%s%s  %s", cursor, formatIncidentLine(g, 20), g.Incident.Summary())
+		}
 
 		sb.WriteString(line)
 		sb.WriteString("\n")
@@ -542,7 +669,12 @@ func (m Model) buildZoomedErrorsPane(width, height int) string {
 	// Show current group info
 	if m.groupCursor < len(m.groups) {
 		g := m.groups[m.groupCursor]
-		sb.WriteString(fmt.Sprintf("Group: %s %s (%d errors)\n\n", g.Time, g.Component, len(errors)))
+		if g.Incident != nil {
+			sb.WriteString(incidentDetails(g.Incident, m.displayLoc))
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("Group: %s (%d errors)\n\n", groupLabel(g), len(errors)))
+		}
 	}
 
 	// Calculate visible range
@@ -578,6 +710,9 @@ func (m Model) buildZoomedErrorsPane(width, height int) string {
 			msg = msg[:msgWidth-3] + "..."
 		}
 		line := fmt.Sprintf("%s[%s] %s", cursor, e.LogLevel, msg)
+		if m.messageFilter != "" {
+			line = markFilter(line, lipgloss.Width(line)-lipgloss.Width(msg), msg, m.messageFilter, false)
+		}
 
 		sb.WriteString(line)
 		sb.WriteString("\n")
@@ -601,11 +736,15 @@ func (m Model) buildZoomedContextPane(width, height int) string {
 		return sb.String()
 	}
 
-	e := errors[m.errorCursor]
+	e, loaded := m.withContext(errors[m.errorCursor])
 
 	// Header info
+	sb.WriteString(fmt.Sprintf("Time: %s\n", errorTimeLabel(e, m.displayLoc)))
 	sb.WriteString(fmt.Sprintf("Component: %s @ %s\n", e.Component, e.Host))
 	sb.WriteString(fmt.Sprintf("File: %s:%d\n", e.FilePath, e.LineNumber))
+	if e.Source != "" {
+		sb.WriteString(fmt.Sprintf("DB: %s\n", e.Source))
+	}
 	sb.WriteString(fmt.Sprintf("Type: %s  Level: %s\n\n", e.ErrorType, e.LogLevel))
 
 	// Context before
@@ -615,9 +754,9 @@ func (m Model) buildZoomedContextPane(width, height int) string {
 		for i, line := range lines {
 			lineNum := startLine + i
 			if lineNum > 0 {
-				sb.WriteString(fmt.Sprintf("%4d  %s\n", lineNum, line))
+				sb.WriteString(fmt.Sprintf("%4d  %s\n", lineNum, highlightLog(line)))
 			} else {
-				sb.WriteString(fmt.Sprintf("      %s\n", line))
+				sb.WriteString(fmt.Sprintf("      %s\n", highlightLog(line)))
 			}
 		}
 	}
@@ -630,9 +769,13 @@ func (m Model) buildZoomedContextPane(width, height int) string {
 		lines := strings.Split(e.ContextAfter, "\n")
 		for i, line := range lines {
 			lineNum := e.LineNumber + i + 1
-			sb.WriteString(fmt.Sprintf("%4d  %s\n", lineNum, line))
+			sb.WriteString(fmt.Sprintf("%4d  %s\n", lineNum, highlightLog(line)))
 		}
 	}
+	if !loaded {
+		sb.WriteString("\n" + m.contextPending() + "\n")
+	}
 
-	return sb.String()
+	text := sb.String()
+	return highlightMatches(text, findMatches(text, m.search.query), -1)
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+
+	"github.com/carbonscott/lcls-daq-browser/daqlog"
+)
+
+// displayZones returns the zones the Z key cycles through: the starting zone
+// first, then Pacific, UTC and local time
+func displayZones(start *time.Location) []*time.Location {
+	if start == nil {
+		start = daqlog.PacificLoc
+	}
+	zones := []*time.Location{start}
+	for _, loc := range []*time.Location{daqlog.PacificLoc, time.UTC, time.Local} {
+		if loc.String() != start.String() {
+			zones = append(zones, loc)
+		}
+	}
+	return zones
+}
+
+// zoneName is the short zone name shown in titles
+func zoneName(loc *time.Location) string {
+	switch loc {
+	case daqlog.PacificLoc:
+		return "Pacific"
+	case time.UTC:
+		return "UTC"
+	case time.Local:
+		return "local"
+	}
+	return loc.String()
+}
+
+// cycleZone switches to the next display zone and relabels everything,
+// keeping the selection
+func (m *Model) cycleZone() {
+	m.zoneIdx = (m.zoneIdx + 1) % len(m.zones)
+	m.displayLoc = m.zones[m.zoneIdx]
+	m.regroupKeepSelection()
+}
+
+// errorTimeLabel renders an error's full time in loc with its zone
+// abbreviation and source, e.g. "2025-11-02 01:30:12 PST -08 (db)"
+func errorTimeLabel(e daqlog.Error, loc *time.Location) string {
+	t, ok := daqlog.ErrorTime(e)
+	if !ok {
+		return "unknown"
+	}
+	return daqlog.FormatClock(t, loc, "2006-01-02 15:04:05 MST") + " (" + e.TimeSource.String() + ")"
+}